@@ -0,0 +1,176 @@
+package main
+
+import (
+	"backend-ewallet/migrations"
+	"backend-ewallet/models"
+	"backend-ewallet/utils"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runCommand handles the maintenance subcommands, `go run . <command>`.
+func runCommand(args []string) {
+	switch args[0] {
+	case "normalize-identities":
+		normalizeIdentities(args[1:])
+	case "migrate":
+		migrate(args[1:])
+	case "ledger-check":
+		ledgerCheck(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\ncommands:\n", args[0])
+		fmt.Fprintln(os.Stderr, "  ledger-check [-fix]               verify the ledger and rebuild drifted cached balances")
+		fmt.Fprintln(os.Stderr, "  migrate up [-steps N]             apply pending schema migrations")
+		fmt.Fprintln(os.Stderr, "  migrate down [-steps N]           revert the last N migrations (default 1)")
+		fmt.Fprintln(os.Stderr, "  migrate status                    list migrations and whether they are applied")
+		fmt.Fprintln(os.Stderr, "  migrate seed                      insert the reference data (payment methods)")
+		fmt.Fprintln(os.Stderr, "  normalize-identities [-dry-run]   rewrite emails and phones into canonical form and deduplicate users")
+		os.Exit(2)
+	}
+}
+
+func normalizeIdentities(args []string) {
+	flags := flag.NewFlagSet("normalize-identities", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only report what would change")
+	flags.Parse(args)
+
+	db, err := utils.NewDBPool()
+	if err != nil {
+		log.Fatal("Failed to connect to the database: ", err)
+	}
+	defer db.Close()
+
+	report, err := models.NormalizeIdentities(db, *dryRun)
+	if err != nil {
+		log.Fatal("Failed to normalize identities: ", err)
+	}
+
+	sections := []struct {
+		title string
+		lines []string
+	}{
+		{"Normalized", report.Normalized},
+		{"Deactivated duplicates", report.Deactivated},
+		{"Conflicts left untouched", report.Conflicts},
+		{"Invalid values left untouched", report.Invalid},
+	}
+	for _, section := range sections {
+		fmt.Printf("%s: %d\n", section.title, len(section.lines))
+		for _, line := range section.lines {
+			fmt.Println("  " + line)
+		}
+	}
+	if *dryRun {
+		fmt.Println("Dry run, nothing was written")
+	}
+}
+
+func migrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: migrate up|down|status|seed")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	defaultSteps := 0
+	if args[0] == "down" {
+		defaultSteps = 1
+	}
+	steps := flags.Int("steps", defaultSteps, "number of migrations, 0 applies all pending ones")
+	flags.Parse(args[1:])
+
+	db, err := utils.NewDBPool()
+	if err != nil {
+		log.Fatal("Failed to connect to the database: ", err)
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(db, *steps)
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Failed to migrate: ", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+	case "down":
+		if *steps < 1 {
+			log.Fatal("-steps must be at least 1")
+		}
+		reverted, err := migrations.Down(db, *steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Failed to revert: ", err)
+		}
+	case "status":
+		statuses, err := migrations.Status(db)
+		if err != nil {
+			log.Fatal("Failed to read migration status: ", err)
+		}
+		for _, status := range statuses {
+			appliedAt := ""
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %-8s %s\n", status.Version, status.Name, status.State, appliedAt)
+		}
+	case "seed":
+		seeds, err := migrations.Seed(db)
+		for _, seed := range seeds {
+			fmt.Println("Seeded " + seed)
+		}
+		if err != nil {
+			log.Fatal("Failed to seed: ", err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q, use up, down, status or seed\n", args[0])
+		os.Exit(2)
+	}
+}
+
+func ledgerCheck(args []string) {
+	flags := flag.NewFlagSet("ledger-check", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "rewrite cached balances that differ from the ledger")
+	flags.Parse(args)
+
+	db, err := utils.NewDBPool()
+	if err != nil {
+		log.Fatal("Failed to connect to the database: ", err)
+	}
+	defer db.Close()
+
+	report, err := models.CheckLedger(db, *fix)
+	if err != nil {
+		log.Fatal("Failed to check the ledger: ", err)
+	}
+
+	sections := []struct {
+		title string
+		lines []string
+	}{
+		{"Unbalanced journal entries", report.Unbalanced},
+		{"Cached balances differing from the ledger", report.Drifted},
+		{"System accounts", report.SystemBalances},
+	}
+	for _, section := range sections {
+		fmt.Printf("%s: %d\n", section.title, len(section.lines))
+		for _, line := range section.lines {
+			fmt.Println("  " + line)
+		}
+	}
+	if len(report.Drifted) > 0 {
+		if *fix {
+			fmt.Println("Cached balances rebuilt from the ledger")
+		} else {
+			fmt.Println("Run with -fix to rebuild them from the ledger")
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"archive/zip"
+	"backend-ewallet/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (uc *UserController) CloseAccount(c *gin.Context) {
+	var req models.CloseAccountRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	user, err := uc.userRepo.GetUserByID(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	validPin, pinStatus, err := verifyPin(uc.userRepo, user, req.Pin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to verify PIN",
+		})
+		return
+	}
+	if !validPin {
+		pinErrorResponse(c, pinStatus)
+		return
+	}
+
+	if err := uc.userRepo.CloseAccount(user.UserID); err != nil {
+		switch err {
+		case models.ErrAccountHasBalance:
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Error:   "Transfer out your remaining balance before closing the account",
+				Data:    gin.H{"balance": user.Balance},
+			})
+		case models.ErrAccountHasPendingTransactions:
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Error:   "The account has pending transactions, try again once they are settled",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Error:   "Failed to close account",
+			})
+		}
+		return
+	}
+
+	// The address is gone from the database, this is the last message it gets
+	body := "Hi " + user.FullName + ",\n\n" +
+		"Your wallet was closed on " + time.Now().Format(time.RFC1123) + " and your personal data has been removed.\n" +
+		"If you didn't do this, contact support right away."
+	if err := uc.notifier.Send(user.Email, "Your wallet has been closed", body); err != nil {
+		log.Printf("Failed to send closure notice to user %d: %v", user.UserID, err)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Account closed successfully",
+	})
+}
+
+// ExportAccount returns the profile, contacts and full transaction history as
+// a JSON download, or as a ZIP of one JSON file per part with ?format=zip.
+func (uc *UserController) ExportAccount(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Format must be json or zip",
+		})
+		return
+	}
+
+	user, err := uc.userRepo.GetUserByID(c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	contacts, err := uc.contactRepo.GetContactsByUserID(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to get contacts",
+		})
+		return
+	}
+
+	transactions, err := uc.transactionRepo.GetAllTransactionsByUserID(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to get transactions",
+		})
+		return
+	}
+
+	export := models.AccountExport{
+		ExportedAt:   time.Now(),
+		Profile:      *user,
+		Contacts:     contacts,
+		Transactions: transactions,
+	}
+
+	filename := fmt.Sprintf("account-export-%d-%s", user.UserID, export.ExportedAt.Format("20060102"))
+	var data []byte
+	contentType := "application/json"
+	if format == "zip" {
+		data, err = zipAccountExport(&export)
+		contentType = "application/zip"
+	} else {
+		data, err = json.MarshalIndent(export, "", "  ")
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to build export",
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func zipAccountExport(export *models.AccountExport) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	parts := []struct {
+		name    string
+		content any
+	}{
+		{"profile.json", export.Profile},
+		{"contacts.json", export.Contacts},
+		{"transactions.json", export.Transactions},
+	}
+	for _, part := range parts {
+		data, err := json.MarshalIndent(part.content, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     part.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,366 @@
+package controllers
+
+import (
+	"backend-ewallet/models"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AdminController struct {
+	userRepo        *models.UserRepository
+	transactionRepo *models.TransactionRepository
+	auditLogRepo    *models.AuditLogRepository
+}
+
+func NewAdminController(db *pgxpool.Pool) *AdminController {
+	return &AdminController{
+		userRepo:        models.NewUserRepository(db),
+		transactionRepo: models.NewTransactionRepository(db),
+		auditLogRepo:    models.NewAuditLogRepository(db),
+	}
+}
+
+// audit writes a back-office read to the audit trail. A failure to do so
+// aborts the request, an unaudited admin action is not acceptable. Actions
+// that change data write their entry in the same transaction instead, see
+// newAuditEntry.
+func (ac *AdminController) audit(c *gin.Context, action string, targetUserID *int, details gin.H) bool {
+	entry := newAuditEntry(c, action, targetUserID, details)
+	if err := ac.auditLogRepo.CreateAuditLog(entry); err != nil {
+		log.Printf("Failed to write audit log %s: %v", action, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to write audit log",
+		})
+		return false
+	}
+	return true
+}
+
+// newAuditEntry describes the back-office action for the audit trail, for
+// repositories that write it in the same transaction as the change.
+func newAuditEntry(c *gin.Context, action string, targetUserID *int, details gin.H) *models.AuditLog {
+	raw, _ := json.Marshal(details)
+	return &models.AuditLog{
+		ActorID:      c.GetInt("user_id"),
+		Action:       action,
+		TargetUserID: targetUserID,
+		Details:      raw,
+		IPAddress:    c.ClientIP(),
+		CreatedAt:    time.Now(),
+	}
+}
+
+// pagination reads page and limit from the query string, 20 per page by default.
+func pagination(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// targetUser loads the user from the :id param, writing the error response itself.
+func (ac *AdminController) targetUser(c *gin.Context) (*models.User, bool) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid user id",
+		})
+		return nil, false
+	}
+
+	user, err := ac.userRepo.GetAnyUserByID(userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "User not found",
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (ac *AdminController) SearchUsers(c *gin.Context) {
+	keyword := c.Query("q")
+	page, limit := pagination(c)
+
+	if !ac.audit(c, "users.search", nil, gin.H{"q": keyword, "page": page}) {
+		return
+	}
+
+	users, total, err := ac.userRepo.SearchUsers(keyword, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to search users",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Users retrieved successfully",
+		Data:    users,
+		Meta:    models.NewMeta(page, limit, total),
+	})
+}
+
+func (ac *AdminController) GetUser(c *gin.Context) {
+	user, ok := ac.targetUser(c)
+	if !ok {
+		return
+	}
+
+	if !ac.audit(c, "users.view", &user.UserID, nil) {
+		return
+	}
+
+	transactions, err := ac.transactionRepo.GetTransactionsByUserID(user.UserID, 10)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to get transactions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "User retrieved successfully",
+		Data: models.AdminUserDetail{
+			User:               *user,
+			RecentTransactions: transactions,
+		},
+	})
+}
+
+func (ac *AdminController) SuspendUser(c *gin.Context) {
+	user, ok := ac.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdminActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if user.UserID == c.GetInt("user_id") {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "You cannot suspend your own account",
+		})
+		return
+	}
+
+	if user.RegistrationStatus == "suspended" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "User is already suspended",
+		})
+		return
+	}
+
+	entry := newAuditEntry(c, "users.suspend", &user.UserID, gin.H{
+		"reason":          req.Reason,
+		"previous_status": user.RegistrationStatus,
+	})
+	if err := ac.userRepo.SuspendUser(user.UserID, user.RegistrationStatus, entry); err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Error:   "User status changed, please try again",
+			})
+			return
+		}
+		log.Printf("Failed to suspend user %d: %v", user.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to suspend user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "User suspended successfully",
+	})
+}
+
+func (ac *AdminController) ReactivateUser(c *gin.Context) {
+	user, ok := ac.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdminActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if user.RegistrationStatus != "suspended" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "User is not suspended",
+		})
+		return
+	}
+
+	// The status from before the suspension comes back, so an account that
+	// never verified its email stays pending
+	status := "pending"
+	if user.SuspendedFrom != nil {
+		status = *user.SuspendedFrom
+	}
+	entry := newAuditEntry(c, "users.reactivate", &user.UserID, gin.H{
+		"reason":          req.Reason,
+		"restored_status": status,
+	})
+	if err := ac.userRepo.ReactivateUser(user.UserID, status, entry); err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Error:   "User status changed, please try again",
+			})
+			return
+		}
+		log.Printf("Failed to reactivate user %d: %v", user.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to reactivate user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "User reactivated successfully",
+	})
+}
+
+func (ac *AdminController) ForceLogout(c *gin.Context) {
+	user, ok := ac.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdminActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	entry := newAuditEntry(c, "users.force_logout", &user.UserID, gin.H{"reason": req.Reason})
+	if err := ac.userRepo.ForceLogout(user.UserID, entry); err != nil {
+		log.Printf("Failed to log out user %d: %v", user.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to revoke sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "User logged out from all devices",
+	})
+}
+
+func (ac *AdminController) ResetPinLockout(c *gin.Context) {
+	user, ok := ac.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdminActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	entry := newAuditEntry(c, "users.reset_pin_lockout", &user.UserID, gin.H{
+		"reason":           req.Reason,
+		"pin_locked_until": user.PinLockedUntil,
+	})
+	if err := ac.userRepo.ResetPinLockout(user.UserID, entry); err != nil {
+		log.Printf("Failed to reset PIN lockout of user %d: %v", user.UserID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to reset PIN lockout",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "PIN lockout reset successfully",
+	})
+}
+
+func (ac *AdminController) GetAuditLogs(c *gin.Context) {
+	page, limit := pagination(c)
+
+	var targetUserID *int
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   "Invalid user id",
+			})
+			return
+		}
+		targetUserID = &id
+	}
+
+	logs, total, err := ac.auditLogRepo.GetAuditLogs(targetUserID, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to get audit logs",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Audit logs retrieved successfully",
+		Data:    logs,
+		Meta:    models.NewMeta(page, limit, total),
+	})
+}
@@ -3,20 +3,49 @@ package controllers
 import (
 	"backend-ewallet/models"
 	"backend-ewallet/utils"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	passwordResetTTL      = 30 * time.Minute
+	emailVerificationTTL  = 24 * time.Hour
+	verificationResendGap = time.Minute
 )
 
 type AuthController struct {
-	userRepo *models.UserRepository
+	userRepo          models.UserStore
+	passwordResetRepo *models.PasswordResetRepository
+	verificationRepo  *models.EmailVerificationRepository
+	refreshTokenRepo  *models.RefreshTokenRepository
+	revokedTokenRepo  *models.RevokedTokenRepository
+	sessionRepo       *models.SessionRepository
+	otpRepo           *models.OTPRepository
+	mfaRepo           *models.MFARepository
+	loginFailureRepo  *models.LoginFailureRepository
+	notifier          utils.Notifier
+	smsSender         utils.SMSSender
 }
 
-func NewAuthController() *AuthController {
+func NewAuthController(db *pgxpool.Pool, userRepo models.UserStore) *AuthController {
 	return &AuthController{
-		userRepo: models.NewUserRepository(),
+		userRepo:          userRepo,
+		passwordResetRepo: models.NewPasswordResetRepository(db),
+		verificationRepo:  models.NewEmailVerificationRepository(db),
+		refreshTokenRepo:  models.NewRefreshTokenRepository(db),
+		revokedTokenRepo:  models.NewRevokedTokenRepository(db),
+		sessionRepo:       models.NewSessionRepository(db),
+		otpRepo:           models.NewOTPRepository(db),
+		mfaRepo:           models.NewMFARepository(db),
+		loginFailureRepo:  models.NewLoginFailureRepository(db),
+		notifier:          utils.NewNotifier(),
+		smsSender:         utils.NewSMSSender(),
 	}
 }
 
@@ -30,6 +59,14 @@ func (ctrl *AuthController) Register(c *gin.Context) {
 		return
 	}
 
+	if err := req.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	// Check if user already exists
 	existingUser, _ := ctrl.userRepo.GetUserByEmail(req.Email)
 	if existingUser != nil {
@@ -50,6 +87,13 @@ func (ctrl *AuthController) Register(c *gin.Context) {
 		return
 	}
 
+	policyErrors := models.FieldErrors{}
+	checkPassword(policyErrors, "password", req.Password, req.Email, req.Phone, req.FullName)
+	checkPin(policyErrors, "pin", req.Pin, req.Phone)
+	if rejectWeakCredentials(c, policyErrors) {
+		return
+	}
+
 	// Hash password and PIN
 	passwordHash, err := utils.HashPassword(req.Password)
 	if err != nil {
@@ -76,8 +120,9 @@ func (ctrl *AuthController) Register(c *gin.Context) {
 		FullName:           req.FullName,
 		PasswordHash:       passwordHash,
 		PinHash:            pinHash,
-		Balance:            0.0,
-		RegistrationStatus: "completed",
+		Balance:            0,
+		RegistrationStatus: "pending",
+		Role:               models.RoleUser,
 		CreatedAt:          time.Now(),
 		UpdatedAt:          time.Now(),
 		IsActive:           true,
@@ -91,17 +136,132 @@ func (ctrl *AuthController) Register(c *gin.Context) {
 		return
 	}
 
+	// The user can ask for another email or code later, so a delivery failure is not fatal
+	if err := sendVerificationEmail(ctrl.verificationRepo, ctrl.notifier, user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.UserID, err)
+	}
+	if err := sendOTP(ctrl.otpRepo, ctrl.smsSender, user, models.OTPPurposePhoneVerification); err != nil {
+		log.Printf("Failed to send phone verification code to user %d: %v", user.UserID, err)
+	}
+
 	// Remove sensitive data
 	user.PasswordHash = ""
 	user.PinHash = ""
 
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
-		Message: "User registered successfully",
+		Message: "User registered successfully, please verify your email",
 		Data:    user,
 	})
 }
 
+func sendVerificationEmail(verificationRepo *models.EmailVerificationRepository, notifier utils.Notifier, user *models.User) error {
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(emailVerificationTTL)
+	if err := verificationRepo.CreateVerification(user.UserID, utils.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	body := "Welcome " + user.FullName + ",\n\n" +
+		"Confirm your email by opening: /auth/verify-email?token=" + token +
+		"\nThe link expires at " + expiresAt.Format(time.RFC1123) + "."
+	return notifier.Send(user.Email, "Verify your email", body)
+}
+
+func (ctrl *AuthController) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Verification token is required",
+		})
+		return
+	}
+
+	if err := ctrl.verificationRepo.ConsumeVerification(utils.HashToken(token)); err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   "Invalid or expired verification token",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to verify email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Email verified successfully",
+	})
+}
+
+func (ctrl *AuthController) ResendVerification(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	user, err := ctrl.userRepo.GetUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	if user.RegistrationStatus != "pending" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Account does not need verification",
+		})
+		return
+	}
+
+	lastSentAt, err := ctrl.verificationRepo.GetLastSentAt(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+	if lastSentAt != nil && time.Since(*lastSentAt) < verificationResendGap {
+		retryAfter := verificationResendGap - time.Since(*lastSentAt)
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, models.APIResponse{
+			Success: false,
+			Error:   "Please wait before requesting another verification email",
+		})
+		return
+	}
+
+	if err := sendVerificationEmail(ctrl.verificationRepo, ctrl.notifier, user); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to send verification email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Verification email sent",
+	})
+}
+
 func (ctrl *AuthController) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBind(&req); err != nil {
@@ -112,13 +272,147 @@ func (ctrl *AuthController) Login(c *gin.Context) {
 		return
 	}
 
+	if err := req.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	invalidCredentials := models.APIResponse{
+		Success: false,
+		Error:   "Invalid credentials",
+	}
+
+	// Lockout is tracked per email, so unknown emails behave exactly like real ones
+	failures, ok := ctrl.allowLoginAttempt(c, req.Email)
+	if !ok {
+		return
+	}
+
 	// Get user by email
 	user, err := ctrl.userRepo.GetUserByEmail(req.Email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			utils.CheckPasswordHash(req.Password, dummyHash())
+			ctrl.registerLoginFailure(c, req.Email, nil, failures)
+			c.JSON(http.StatusUnauthorized, invalidCredentials)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	// Check password
+	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
+		ctrl.registerLoginFailure(c, req.Email, user, failures)
+		c.JSON(http.StatusUnauthorized, invalidCredentials)
+		return
+	}
+
+	rehashPassword(ctrl.userRepo, user, req.Password)
+
+	ctrl.completeLogin(c, user, req.DeviceLabel)
+}
+
+func accessClaims(user *models.User, sessionID int) utils.AccessClaims {
+	return utils.AccessClaims{
+		UserID:       user.UserID,
+		TokenVersion: user.TokenVersion,
+		SessionID:    sessionID,
+		Role:         user.Role,
+		Permissions:  models.PermissionsForRole(user.Role),
+	}
+}
+
+// issueTokens records a new session for the client and mints an access token
+// plus the first refresh token of a new family bound to that session.
+func (ctrl *AuthController) issueTokens(c *gin.Context, user *models.User, deviceLabel string) (*models.TokenResponse, error) {
+	session := &models.Session{
+		UserID:      user.UserID,
+		UserAgent:   c.Request.UserAgent(),
+		IPAddress:   c.ClientIP(),
+		DeviceLabel: deviceLabel,
+		CreatedAt:   time.Now(),
+		LastSeenAt:  time.Now(),
+	}
+	if err := ctrl.sessionRepo.CreateSession(session); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateToken(accessClaims(user, session.SessionID))
+	if err != nil {
+		return nil, err
+	}
+
+	familyID, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctrl.refreshTokenRepo.CreateRefreshToken(user.UserID, session.SessionID, familyID,
+		utils.HashToken(refreshToken), time.Now().Add(utils.RefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (ctrl *AuthController) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	newRefreshToken, err := utils.GenerateSecureToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to generate token",
+		})
+		return
+	}
+
+	oldToken, err := ctrl.refreshTokenRepo.RotateRefreshToken(utils.HashToken(req.RefreshToken),
+		utils.HashToken(newRefreshToken), time.Now().Add(utils.RefreshTokenTTL))
+	if err != nil {
+		if err == pgx.ErrNoRows || err == models.ErrRefreshTokenReused {
+			c.JSON(http.StatusUnauthorized, models.APIResponse{
+				Success: false,
+				Error:   "Invalid refresh token",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to refresh token",
+		})
+		return
+	}
+
+	user, err := ctrl.userRepo.GetUserByID(oldToken.UserID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			c.JSON(http.StatusUnauthorized, models.APIResponse{
 				Success: false,
-				Error:   "Invalid credentials",
+				Error:   "Invalid refresh token",
 			})
 			return
 		}
@@ -129,20 +423,20 @@ func (ctrl *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	// Check password
-	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
-		c.JSON(http.StatusUnauthorized, models.APIResponse{
+	if user.RegistrationStatus == "suspended" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
 			Success: false,
-			Error:   "Invalid credentials",
+			Error:   "Account is suspended",
 		})
 		return
 	}
 
-	// Update last login
-	ctrl.userRepo.UpdateLastLogin(user.UserID)
+	var sessionID int
+	if oldToken.SessionID != nil {
+		sessionID = *oldToken.SessionID
+	}
 
-	// Generate token
-	token, err := utils.GenerateToken(user.UserID)
+	token, err := utils.GenerateToken(accessClaims(user, sessionID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
@@ -153,15 +447,16 @@ func (ctrl *AuthController) Login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "Login successful",
-		Data: models.LoginResponse{
-			Token: token,
-			User:  *user,
+		Message: "Token refreshed successfully",
+		Data: models.TokenResponse{
+			Token:        token,
+			RefreshToken: newRefreshToken,
+			ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
 		},
 	})
 }
 
-func (ctrl *AuthController) GetProfile(c *gin.Context) {
+func (ctrl *AuthController) Logout(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.APIResponse{
@@ -171,12 +466,214 @@ func (ctrl *AuthController) GetProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := ctrl.userRepo.GetUserByID(userID.(int))
+	expiresAt := time.Now().Add(utils.AccessTokenTTL)
+	if exp, ok := c.Get("token_exp"); ok {
+		expiresAt = exp.(time.Time)
+	}
+
+	if err := ctrl.revokedTokenRepo.RevokeToken(c.GetString("jti"), userID.(int), expiresAt); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to revoke token",
+		})
+		return
+	}
+
+	err := ctrl.sessionRepo.RevokeSession(c.GetInt("session_id"), userID.(int))
+	if err != nil && err != pgx.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to revoke session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Logged out successfully",
+	})
+}
+
+func (ctrl *AuthController) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	if err := ctrl.sessionRepo.RevokeAllUserSessions(userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to revoke sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Logged out from all devices",
+	})
+}
+
+func (ctrl *AuthController) GetSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	sessions, err := ctrl.sessionRepo.GetActiveSessionsByUserID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to get sessions",
+		})
+		return
+	}
+
+	currentSessionID := c.GetInt("session_id")
+	for i := range sessions {
+		sessions[i].IsCurrent = sessions[i].SessionID == currentSessionID
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Sessions retrieved successfully",
+		Data:    sessions,
+	})
+}
+
+func (ctrl *AuthController) DeleteSession(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	sessionID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid session id",
+		})
+		return
+	}
+
+	if err := ctrl.sessionRepo.RevokeSession(sessionID, userID.(int)); err != nil {
 		if err == pgx.ErrNoRows {
 			c.JSON(http.StatusNotFound, models.APIResponse{
 				Success: false,
-				Error:   "User not found",
+				Error:   "Session not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to revoke session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Session revoked successfully",
+	})
+}
+
+func (ctrl *AuthController) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := req.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Same response whether the email exists or not, to avoid leaking accounts
+	response := models.APIResponse{
+		Success: true,
+		Message: "If the email is registered, a reset link has been sent",
+	}
+
+	user, err := ctrl.userRepo.GetUserByEmail(req.Email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	token, err := utils.GenerateSecureToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to generate reset token",
+		})
+		return
+	}
+
+	expiresAt := time.Now().Add(passwordResetTTL)
+	if err := ctrl.passwordResetRepo.CreateReset(user.UserID, utils.HashToken(token), expiresAt); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to create reset token",
+		})
+		return
+	}
+
+	body := "Use this token to reset your password: " + token +
+		"\nIt expires at " + expiresAt.Format(time.RFC1123) + "."
+	if err := ctrl.notifier.Send(user.Email, "Reset your password", body); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to send reset token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (ctrl *AuthController) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	tokenHash := utils.HashToken(req.Token)
+	userID, err := ctrl.passwordResetRepo.GetResetUserID(tokenHash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   "Invalid or expired reset token",
 			})
 			return
 		}
@@ -187,13 +684,47 @@ func (ctrl *AuthController) GetProfile(c *gin.Context) {
 		return
 	}
 
-	// Remove sensitive data
-	user.PasswordHash = ""
-	user.PinHash = ""
+	user, err := ctrl.userRepo.GetAnyUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	policyErrors := models.FieldErrors{}
+	checkPassword(policyErrors, "new_password", req.NewPassword, user.Email, user.Phone, user.FullName)
+	if rejectWeakCredentials(c, policyErrors) {
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to hash password",
+		})
+		return
+	}
+
+	if err := ctrl.passwordResetRepo.ConsumeReset(tokenHash, passwordHash); err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   "Invalid or expired reset token",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to reset password",
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
-		Message: "Profile retrieved successfully",
-		Data:    user,
+		Message: "Password reset successfully",
 	})
 }
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"backend-ewallet/utils"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HealthController struct {
+	db *pgxpool.Pool
+}
+
+func NewHealthController(db *pgxpool.Pool) *HealthController {
+	return &HealthController{db: db}
+}
+
+// ping reports whether the database answers within two seconds.
+func (hc *HealthController) ping(c *gin.Context) (int, string) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := hc.db.Ping(ctx); err != nil {
+		return http.StatusServiceUnavailable, "unreachable"
+	}
+	return http.StatusOK, "ok"
+}
+
+// GetHealth is the public probe: whether the service and its database are
+// up, 503 when the database can't be reached.
+func (hc *HealthController) GetHealth(c *gin.Context) {
+	status, database := hc.ping(c)
+	c.JSON(status, gin.H{
+		"status":   http.StatusText(status),
+		"database": database,
+	})
+}
+
+// GetPoolHealth adds the connection pool usage for operators.
+func (hc *HealthController) GetPoolHealth(c *gin.Context) {
+	status, database := hc.ping(c)
+	c.JSON(status, gin.H{
+		"status":   http.StatusText(status),
+		"database": database,
+		"pool":     utils.PoolStats(hc.db),
+	})
+}
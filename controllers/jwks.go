@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"backend-ewallet/models"
+	"backend-ewallet/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS publishes the public keys access tokens are verified with, so other
+// services can check our tokens without sharing a secret.
+func GetJWKS(c *gin.Context) {
+	set, err := utils.PublicJWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to load signing keys",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"backend-ewallet/models"
+	"backend-ewallet/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	loginLockThreshold = 5
+	loginLockBase      = time.Minute
+	loginLockMax       = time.Hour
+	loginFailureWindow = 24 * time.Hour
+
+	ipFailureLimit  = 20
+	ipFailureWindow = 15 * time.Minute
+)
+
+// dummyPasswordHash is compared against when the email is unknown so that the
+// response time doesn't reveal whether an account exists. It is made on first
+// use, after the configuration is loaded, so it costs what real hashes cost.
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
+func dummyHash() string {
+	dummyPasswordHashOnce.Do(func() {
+		hash, err := utils.HashPassword("not-a-real-password")
+		if err != nil {
+			log.Printf("Failed to create the dummy password hash: %v", err)
+			return
+		}
+		dummyPasswordHash = hash
+	})
+	return dummyPasswordHash
+}
+
+// loginLockedUntil applies exponential backoff: the lock starts at
+// loginLockBase once loginLockThreshold failures pile up and doubles with
+// every further failure, up to loginLockMax.
+func loginLockedUntil(failures int, lastFailure *time.Time) *time.Time {
+	if failures < loginLockThreshold || lastFailure == nil {
+		return nil
+	}
+
+	lockFor := loginLockBase
+	for i := loginLockThreshold; i < failures && lockFor < loginLockMax; i++ {
+		lockFor *= 2
+	}
+	if lockFor > loginLockMax {
+		lockFor = loginLockMax
+	}
+
+	until := lastFailure.Add(lockFor)
+	return &until
+}
+
+// allowLoginAttempt applies the limits every login method shares: failures
+// per client IP and the per-account lockout, tracked under account (an
+// email, or the identifier typed in when it matches no account). It responds
+// and returns false when the attempt must not go ahead, otherwise it returns
+// the account's recent failures for registerLoginFailure.
+func (ctrl *AuthController) allowLoginAttempt(c *gin.Context, account string) (int, bool) {
+	ipFailures, err := ctrl.loginFailureRepo.CountIPFailures(c.ClientIP(), time.Now().Add(-ipFailureWindow))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return 0, false
+	}
+	if ipFailures >= ipFailureLimit {
+		recordLoginFailure(ctrl.loginFailureRepo, c, account, nil, models.LoginFailureIPThrottled)
+		c.Header("Retry-After", strconv.Itoa(int(ipFailureWindow.Seconds())))
+		c.JSON(http.StatusTooManyRequests, models.APIResponse{
+			Success: false,
+			Error:   "Too many login attempts, please try again later",
+		})
+		return 0, false
+	}
+
+	failures, lastFailure, err := ctrl.loginFailureRepo.GetAccountFailures(account, time.Now().Add(-loginFailureWindow))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return 0, false
+	}
+	if lockedUntil := loginLockedUntil(failures, lastFailure); lockedUntil != nil && time.Now().Before(*lockedUntil) {
+		recordLoginFailure(ctrl.loginFailureRepo, c, account, nil, models.LoginFailureLocked)
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid credentials",
+		})
+		return 0, false
+	}
+
+	return failures, true
+}
+
+// registerLoginFailure counts a wrong credential against the account and
+// tells the owner when it is the one that locks the account.
+func (ctrl *AuthController) registerLoginFailure(c *gin.Context, account string, user *models.User, failures int) {
+	if user == nil {
+		recordLoginFailure(ctrl.loginFailureRepo, c, account, nil, models.LoginFailureInvalidCredentials)
+		return
+	}
+	registerUserFailure(ctrl.loginFailureRepo, ctrl.notifier, c, account, user, failures)
+}
+
+func registerUserFailure(loginFailureRepo *models.LoginFailureRepository, notifier utils.Notifier, c *gin.Context, account string, user *models.User, failures int) {
+	recordLoginFailure(loginFailureRepo, c, account, &user.UserID, models.LoginFailureInvalidCredentials)
+	if failures+1 == loginLockThreshold {
+		now := time.Now()
+		notifyLockout(notifier, user, *loginLockedUntil(failures+1, &now))
+	}
+}
+
+// confirmPassword checks the password a signed-in user gives to confirm a
+// sensitive change. Wrong passwords count against the same lockout as
+// logins, so a stolen access token can't be used to guess the password. It
+// responds and returns false when the request must not go ahead.
+func confirmPassword(c *gin.Context, loginFailureRepo *models.LoginFailureRepository, notifier utils.Notifier, user *models.User, password string) bool {
+	failures, lastFailure, err := loginFailureRepo.GetAccountFailures(user.Email, time.Now().Add(-loginFailureWindow))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return false
+	}
+	if lockedUntil := loginLockedUntil(failures, lastFailure); lockedUntil != nil && time.Now().Before(*lockedUntil) {
+		recordLoginFailure(loginFailureRepo, c, user.Email, &user.UserID, models.LoginFailureLocked)
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(*lockedUntil).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, models.APIResponse{
+			Success: false,
+			Error:   "Too many failed attempts, please try again later",
+		})
+		return false
+	}
+
+	if !utils.CheckPasswordHash(password, user.PasswordHash) {
+		registerUserFailure(loginFailureRepo, notifier, c, user.Email, user, failures)
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid credentials",
+		})
+		return false
+	}
+	return true
+}
+
+func recordLoginFailure(loginFailureRepo *models.LoginFailureRepository, c *gin.Context, email string, userID *int, reason string) {
+	failure := &models.LoginFailure{
+		Email:       email,
+		UserID:      userID,
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Reason:      reason,
+		AttemptedAt: time.Now(),
+	}
+	if err := loginFailureRepo.RecordFailure(failure); err != nil {
+		log.Printf("Failed to record login failure for %s: %v", email, err)
+	}
+}
+
+// notifyLockout tells the owner that the account got locked and how to get out.
+func notifyLockout(notifier utils.Notifier, user *models.User, until time.Time) {
+	body := "We noticed several failed login attempts on your account, so logging in is " +
+		"blocked until " + until.Format(time.RFC1123) + ".\n\n" +
+		"If this wasn't you, reset your password through /auth/forgot-password; " +
+		"doing so also unlocks your account right away."
+	if err := notifier.Send(user.Email, "Your account has been temporarily locked", body); err != nil {
+		log.Printf("Failed to send lockout notice to user %d: %v", user.UserID, err)
+	}
+}
+
+// rehashPassword upgrades a hash made with outdated parameters once the
+// password is known to be right. Failing to do so must not fail the login.
+func rehashPassword(userRepo models.UserStore, user *models.User, password string) {
+	if !utils.PasswordNeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(password)
+	if err == nil {
+		err = userRepo.RehashPassword(user.UserID, user.PasswordHash, passwordHash)
+	}
+	if err != nil {
+		log.Printf("Failed to rehash password of user %d: %v", user.UserID, err)
+		return
+	}
+	user.PasswordHash = passwordHash
+}
@@ -0,0 +1,487 @@
+package controllers
+
+import (
+	"backend-ewallet/models"
+	"backend-ewallet/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	totpIssuer        = "E-Wallet"
+	recoveryCodeCount = 10
+)
+
+// completeLogin is the last step of every primary login method. Users with
+// TOTP enabled get an MFA challenge instead of tokens.
+func (ctrl *AuthController) completeLogin(c *gin.Context, user *models.User, deviceLabel string) {
+	// Credentials were valid, so telling the user about the suspension leaks nothing
+	if user.RegistrationStatus == "suspended" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Error:   "Account is suspended",
+		})
+		return
+	}
+
+	totp, err := ctrl.mfaRepo.GetTOTP(user.UserID)
+	if err != nil && err != pgx.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	if err == nil && totp.ConfirmedAt != nil {
+		token, err := utils.GenerateSecureToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Error:   "Failed to generate MFA token",
+			})
+			return
+		}
+
+		challenge := &models.MFAChallenge{
+			UserID:      user.UserID,
+			TokenHash:   utils.HashToken(token),
+			DeviceLabel: deviceLabel,
+			ExpiresAt:   time.Now().Add(mfaChallengeTTL),
+			CreatedAt:   time.Now(),
+		}
+		if err := ctrl.mfaRepo.CreateChallenge(challenge); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Error:   "Failed to create MFA challenge",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Two-factor authentication required",
+			Data: models.MFAChallengeResponse{
+				MFARequired: true,
+				MFAToken:    token,
+				ExpiresIn:   int(mfaChallengeTTL.Seconds()),
+			},
+		})
+		return
+	}
+
+	ctrl.respondWithTokens(c, user, deviceLabel)
+}
+
+// respondWithTokens finishes a successful login by opening a session. Only
+// now, with every factor passed, is the account's lockout counter cleared.
+func (ctrl *AuthController) respondWithTokens(c *gin.Context, user *models.User, deviceLabel string) {
+	ctrl.loginFailureRepo.ClearFailures(user.Email)
+
+	// Update last login
+	ctrl.userRepo.UpdateLastLogin(user.UserID)
+
+	// Generate token
+	tokens, err := ctrl.issueTokens(c, user, deviceLabel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to generate token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Login successful",
+		Data: models.LoginResponse{
+			Token:        tokens.Token,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    tokens.ExpiresIn,
+			User:         *user,
+		},
+	})
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+func (ctrl *AuthController) verifySecondFactor(totp *models.UserTOTP, code string, recoveryCode string) (bool, error) {
+	if code != "" {
+		secret, err := utils.DecryptString(totp.SecretEncrypted)
+		if err != nil {
+			return false, err
+		}
+		step, ok := utils.ValidateTOTP(secret, code, time.Now(), totp.LastUsedStep)
+		if !ok {
+			return false, nil
+		}
+		if err := ctrl.mfaRepo.UseTOTPStep(totp.UserID, step); err != nil {
+			if err == pgx.ErrNoRows {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	if recoveryCode != "" {
+		if err := ctrl.mfaRepo.UseRecoveryCode(totp.UserID, hashRecoveryCode(recoveryCode)); err != nil {
+			if err == pgx.ErrNoRows {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return utils.HashToken(strings.ReplaceAll(code, "-", ""))
+}
+
+func (ctrl *AuthController) SetupTOTP(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	user, err := ctrl.userRepo.GetUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	totp, err := ctrl.mfaRepo.GetTOTP(user.UserID)
+	if err != nil && err != pgx.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+	if err == nil && totp.ConfirmedAt != nil {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Error:   "Two-factor authentication is already enabled",
+		})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to generate secret",
+		})
+		return
+	}
+
+	secretEncrypted, err := utils.EncryptString(secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to store secret",
+		})
+		return
+	}
+
+	if err := ctrl.mfaRepo.SaveTOTPSecret(user.UserID, secretEncrypted); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to store secret",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Scan the code with your authenticator app, then confirm with a code",
+		Data: models.TOTPSetupResponse{
+			Secret:     secret,
+			OTPAuthURL: utils.TOTPURI(totpIssuer, user.Email, secret),
+		},
+	})
+}
+
+func (ctrl *AuthController) ConfirmTOTP(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	var req models.VerifyOTPRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	totp, err := ctrl.mfaRepo.GetTOTP(userID.(int))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Error:   "Two-factor setup has not been started",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+	if totp.ConfirmedAt != nil {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success: false,
+			Error:   "Two-factor authentication is already enabled",
+		})
+		return
+	}
+
+	secret, err := utils.DecryptString(totp.SecretEncrypted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to read secret",
+		})
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(secret, req.Code, time.Now(), 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid code",
+		})
+		return
+	}
+
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	recoveryHashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.GenerateSecureToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success: false,
+				Error:   "Failed to generate recovery codes",
+			})
+			return
+		}
+		code := raw[:5] + "-" + raw[5:10]
+		recoveryCodes = append(recoveryCodes, code)
+		recoveryHashes = append(recoveryHashes, hashRecoveryCode(code))
+	}
+
+	if err := ctrl.mfaRepo.ConfirmTOTP(userID.(int), step, recoveryHashes); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to enable two-factor authentication",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Two-factor authentication enabled, store the recovery codes somewhere safe",
+		Data: models.RecoveryCodesResponse{
+			RecoveryCodes: recoveryCodes,
+		},
+	})
+}
+
+func (ctrl *AuthController) DisableTOTP(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	var req models.DisableTOTPRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	user, err := ctrl.userRepo.GetUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	if !confirmPassword(c, ctrl.loginFailureRepo, ctrl.notifier, user, req.Password) {
+		return
+	}
+
+	totp, err := ctrl.mfaRepo.GetTOTP(user.UserID)
+	if err != nil || totp.ConfirmedAt == nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Two-factor authentication is not enabled",
+		})
+		return
+	}
+
+	ok, err := ctrl.verifySecondFactor(totp, req.Code, req.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to verify code",
+		})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid code",
+		})
+		return
+	}
+
+	if err := ctrl.mfaRepo.DeleteTOTP(user.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to disable two-factor authentication",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+func (ctrl *AuthController) VerifyMFA(c *gin.Context) {
+	var req models.MFAVerifyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	challenge, err := ctrl.mfaRepo.GetActiveChallenge(utils.HashToken(req.MFAToken))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusUnauthorized, models.APIResponse{
+				Success: false,
+				Error:   "Invalid or expired MFA token",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	user, err := ctrl.userRepo.GetUserByID(challenge.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid credentials",
+		})
+		return
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	failures, ok := ctrl.allowLoginAttempt(c, user.Email)
+	if !ok {
+		return
+	}
+
+	if _, err := ctrl.mfaRepo.ReserveChallengeAttempt(challenge.ChallengeID, models.MFAMaxAttempts); err != nil {
+		if err == pgx.ErrNoRows {
+			ctrl.mfaRepo.ConsumeChallenge(challenge.ChallengeID)
+			c.JSON(http.StatusTooManyRequests, models.APIResponse{
+				Success: false,
+				Error:   "Too many attempts, please log in again",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	// TOTP disabled since the challenge was issued leaves nothing to verify
+	// against, the challenge is dead
+	totp, err := ctrl.mfaRepo.GetTOTP(challenge.UserID)
+	if err == pgx.ErrNoRows || (err == nil && totp.ConfirmedAt == nil) {
+		ctrl.mfaRepo.ConsumeChallenge(challenge.ChallengeID)
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid or expired MFA token",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	ok, err = ctrl.verifySecondFactor(totp, req.Code, req.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to verify code",
+		})
+		return
+	}
+	if !ok {
+		ctrl.registerLoginFailure(c, user.Email, user, failures)
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid code",
+		})
+		return
+	}
+
+	// A challenge can only be redeemed once, even by concurrent requests
+	if err := ctrl.mfaRepo.ConsumeChallenge(challenge.ChallengeID); err != nil {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid or expired MFA token",
+		})
+		return
+	}
+
+	ctrl.respondWithTokens(c, user, challenge.DeviceLabel)
+}
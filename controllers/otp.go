@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"backend-ewallet/models"
+	"backend-ewallet/utils"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	otpTTL        = 5 * time.Minute
+	otpResendGap  = time.Minute
+	otpMaxPerHour = 5
+	otpDigits     = 6
+)
+
+var errOTPThrottled = errors.New("otp requested too often")
+
+// sendOTP issues a new code for the purpose and delivers it by SMS, enforcing
+// the resend gap and the hourly limit.
+func sendOTP(otpRepo *models.OTPRepository, smsSender utils.SMSSender, user *models.User, purpose string) error {
+	lastSentAt, sentLastHour, err := otpRepo.GetSendStats(user.UserID, purpose, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if lastSentAt != nil && time.Since(*lastSentAt) < otpResendGap {
+		return errOTPThrottled
+	}
+	if sentLastHour >= otpMaxPerHour {
+		return errOTPThrottled
+	}
+
+	code, err := utils.GenerateOTP(otpDigits)
+	if err != nil {
+		return err
+	}
+	codeHash, err := utils.HashPassword(code)
+	if err != nil {
+		return err
+	}
+
+	if err := otpRepo.CreateOTP(user.UserID, purpose, codeHash, time.Now().Add(otpTTL)); err != nil {
+		return err
+	}
+
+	message := "E-Wallet: your verification code is " + code +
+		". It expires in 5 minutes. Never share this code with anyone."
+	return smsSender.SendSMS(user.Phone, message)
+}
+
+func otpErrorResponse(c *gin.Context, err error) {
+	switch err {
+	case pgx.ErrNoRows, models.ErrOTPInvalid:
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid or expired code",
+		})
+	case models.ErrOTPTooManyAttempts:
+		c.JSON(http.StatusTooManyRequests, models.APIResponse{
+			Success: false,
+			Error:   "Too many attempts, please request a new code",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to verify code",
+		})
+	}
+}
+
+func (ctrl *AuthController) SendPhoneOTP(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	user, err := ctrl.userRepo.GetUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	if user.PhoneVerifiedAt != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Phone number is already verified",
+		})
+		return
+	}
+
+	if err := sendOTP(ctrl.otpRepo, ctrl.smsSender, user, models.OTPPurposePhoneVerification); err != nil {
+		if err == errOTPThrottled {
+			c.JSON(http.StatusTooManyRequests, models.APIResponse{
+				Success: false,
+				Error:   "Please wait before requesting another code",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to send verification code",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Verification code sent",
+	})
+}
+
+func (ctrl *AuthController) VerifyPhone(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	var req models.VerifyOTPRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := ctrl.otpRepo.VerifyOTP(userID.(int), models.OTPPurposePhoneVerification, req.Code); err != nil {
+		otpErrorResponse(c, err)
+		return
+	}
+
+	if err := ctrl.userRepo.MarkPhoneVerified(userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to verify phone number",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Phone number verified successfully",
+	})
+}
+
+func (ctrl *AuthController) RequestLoginOTP(c *gin.Context) {
+	var req models.RequestLoginOTPRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := req.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Same response whatever happens, so phone numbers can't be probed
+	response := models.APIResponse{
+		Success: true,
+		Message: "If the phone number is registered, a login code has been sent",
+	}
+
+	user, err := ctrl.userRepo.GetUserByPhone(req.Phone)
+	if err != nil || user.PhoneVerifiedAt == nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if err := sendOTP(ctrl.otpRepo, ctrl.smsSender, user, models.OTPPurposeLogin); err != nil && err != errOTPThrottled {
+		log.Printf("Failed to send login code to user %d: %v", user.UserID, err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (ctrl *AuthController) LoginWithOTP(c *gin.Context) {
+	var req models.OTPLoginRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := req.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Failures count against the account's email, shared with password
+	// logins, or against the phone number when it matches no account
+	user, err := ctrl.userRepo.GetUserByPhone(req.Phone)
+	if err != nil && err != pgx.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+	account := req.Phone
+	if user != nil {
+		account = user.Email
+	}
+
+	failures, ok := ctrl.allowLoginAttempt(c, account)
+	if !ok {
+		return
+	}
+
+	if user == nil || user.PhoneVerifiedAt == nil {
+		ctrl.registerLoginFailure(c, account, user, failures)
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "Invalid credentials",
+		})
+		return
+	}
+
+	if err := ctrl.otpRepo.VerifyOTP(user.UserID, models.OTPPurposeLogin, req.Code); err != nil {
+		if err == pgx.ErrNoRows || err == models.ErrOTPInvalid {
+			ctrl.registerLoginFailure(c, account, user, failures)
+			c.JSON(http.StatusUnauthorized, models.APIResponse{
+				Success: false,
+				Error:   "Invalid credentials",
+			})
+			return
+		}
+		otpErrorResponse(c, err)
+		return
+	}
+
+	ctrl.completeLogin(c, user, req.DeviceLabel)
+}
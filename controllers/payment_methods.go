@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"backend-ewallet/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PaymentMethodController struct {
+	paymentMethodRepo *models.PaymentMethodRepository
+}
+
+func NewPaymentMethodController(db *pgxpool.Pool) *PaymentMethodController {
+	return &PaymentMethodController{
+		paymentMethodRepo: models.NewPaymentMethodRepository(db),
+	}
+}
+
+// targetMethod loads the payment method from the :id param, writing the error response itself.
+func (pc *PaymentMethodController) targetMethod(c *gin.Context) (*models.PaymentMethod, bool) {
+	methodID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Invalid payment method id",
+		})
+		return nil, false
+	}
+
+	method, err := pc.paymentMethodRepo.GetAnyPaymentMethodByID(methodID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Payment method not found",
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return nil, false
+	}
+
+	return method, true
+}
+
+func (pc *PaymentMethodController) GetPaymentMethods(c *gin.Context) {
+	methods, err := pc.paymentMethodRepo.GetPaymentMethods(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to get payment methods",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Payment methods retrieved successfully",
+		Data:    methods,
+	})
+}
+
+func (pc *PaymentMethodController) GetAllPaymentMethods(c *gin.Context) {
+	methods, err := pc.paymentMethodRepo.GetPaymentMethods(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to get payment methods",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Payment methods retrieved successfully",
+		Data:    methods,
+	})
+}
+
+func (pc *PaymentMethodController) CreatePaymentMethod(c *gin.Context) {
+	var req models.CreatePaymentMethodRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	method := &models.PaymentMethod{
+		MethodName:    req.MethodName,
+		MethodType:    req.MethodType,
+		IsActive:      req.IsActive == nil || *req.IsActive,
+		MinAmount:     req.MinAmount,
+		MaxAmount:     req.MaxAmount,
+		FeePercentage: req.FeePercentage,
+	}
+
+	audit := func() *models.AuditLog {
+		return newAuditEntry(c, "payment_methods.create", nil, gin.H{"new": method})
+	}
+	if err := pc.paymentMethodRepo.CreatePaymentMethod(method, c.GetInt("user_id"), audit); err != nil {
+		log.Printf("Failed to create payment method: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to create payment method",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Payment method created successfully",
+		Data:    method,
+	})
+}
+
+func (pc *PaymentMethodController) UpdatePaymentMethod(c *gin.Context) {
+	method, ok := pc.targetMethod(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePaymentMethodRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	previous := *method
+	if req.MethodName != nil {
+		method.MethodName = *req.MethodName
+	}
+	if req.MethodType != nil {
+		method.MethodType = *req.MethodType
+	}
+	if req.MinAmount != nil {
+		method.MinAmount = *req.MinAmount
+	}
+	if req.MaxAmount != nil {
+		method.MaxAmount = *req.MaxAmount
+	}
+	if req.FeePercentage != nil {
+		method.FeePercentage = *req.FeePercentage
+	}
+
+	if method.MinAmount > method.MaxAmount {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Minimum amount cannot exceed maximum amount",
+		})
+		return
+	}
+
+	if *method == previous {
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Payment method is unchanged",
+			Data:    method,
+		})
+		return
+	}
+
+	pc.savePaymentMethod(c, &previous, method, "payment_methods.update", nil)
+}
+
+func (pc *PaymentMethodController) EnablePaymentMethod(c *gin.Context) {
+	pc.setPaymentMethodActive(c, true)
+}
+
+func (pc *PaymentMethodController) DisablePaymentMethod(c *gin.Context) {
+	pc.setPaymentMethodActive(c, false)
+}
+
+func (pc *PaymentMethodController) setPaymentMethodActive(c *gin.Context, active bool) {
+	method, ok := pc.targetMethod(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdminActionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	action, state := "payment_methods.disable", "disabled"
+	if active {
+		action, state = "payment_methods.enable", "enabled"
+	}
+
+	if method.IsActive == active {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   "Payment method is already " + state,
+		})
+		return
+	}
+
+	previous := *method
+	method.IsActive = active
+
+	pc.savePaymentMethod(c, &previous, method, action, gin.H{"reason": req.Reason})
+}
+
+// savePaymentMethod stores the change as a new version and audits the old and
+// new values of the method in the same transaction.
+func (pc *PaymentMethodController) savePaymentMethod(c *gin.Context, previous *models.PaymentMethod, method *models.PaymentMethod, action string, details gin.H) {
+	if details == nil {
+		details = gin.H{}
+	}
+	audit := func() *models.AuditLog {
+		details["old"] = previous
+		details["new"] = method
+		return newAuditEntry(c, action, nil, details)
+	}
+	if err := pc.paymentMethodRepo.UpdatePaymentMethod(method, c.GetInt("user_id"), audit); err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "Payment method not found",
+			})
+			return
+		}
+		log.Printf("Failed to update payment method %d: %v", method.MethodID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to update payment method",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Payment method updated successfully",
+		Data:    method,
+	})
+}
+
+func (pc *PaymentMethodController) GetPaymentMethodVersions(c *gin.Context) {
+	method, ok := pc.targetMethod(c)
+	if !ok {
+		return
+	}
+
+	versions, err := pc.paymentMethodRepo.GetPaymentMethodVersions(method.MethodID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to get payment method versions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Payment method versions retrieved successfully",
+		Data:    versions,
+	})
+}
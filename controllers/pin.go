@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"backend-ewallet/models"
+	"backend-ewallet/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pinLockPolicy returns how many wrong PINs are allowed and how long the PIN
+// stays locked afterwards, configurable through PIN_MAX_ATTEMPTS and
+// PIN_LOCK_MINUTES.
+func pinLockPolicy() (int, time.Duration) {
+	maxAttempts := utils.GetEnvInt("PIN_MAX_ATTEMPTS", 3)
+	lockMinutes := utils.GetEnvInt("PIN_LOCK_MINUTES", 30)
+	return maxAttempts, time.Duration(lockMinutes) * time.Minute
+}
+
+// verifyPin checks the PIN against the user's hash while enforcing the lockout.
+// The attempt is counted before the hash is compared, so concurrent guesses
+// can't outrun the lock. When the check fails the returned status describes
+// the lock state.
+func verifyPin(userRepo models.UserStore, user *models.User, pin string) (bool, *models.PinStatus, error) {
+	maxAttempts, lockFor := pinLockPolicy()
+
+	attempts, lockedUntil, err := userRepo.ReservePinAttempt(user.UserID, maxAttempts, time.Now().Add(lockFor))
+	if err == models.ErrPinLocked {
+		return false, &models.PinStatus{
+			Locked:      true,
+			LockedUntil: lockedUntil,
+		}, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+
+	if utils.CheckPasswordHash(pin, user.PinHash) {
+		rehashPin(userRepo, user, pin)
+		if err := userRepo.ResetPinFailures(user.UserID); err != nil {
+			return false, nil, err
+		}
+		return true, nil, nil
+	}
+
+	status := &models.PinStatus{RemainingAttempts: maxAttempts - attempts}
+	if lockedUntil != nil {
+		status.Locked = true
+		status.LockedUntil = lockedUntil
+		status.RemainingAttempts = 0
+	}
+	return false, status, nil
+}
+
+// rehashPin upgrades a PIN hash made with outdated parameters, see rehashPassword.
+func rehashPin(userRepo models.UserStore, user *models.User, pin string) {
+	if !utils.PasswordNeedsRehash(user.PinHash) {
+		return
+	}
+
+	pinHash, err := utils.HashPassword(pin)
+	if err == nil {
+		err = userRepo.RehashPin(user.UserID, user.PinHash, pinHash)
+	}
+	if err != nil {
+		log.Printf("Failed to rehash PIN of user %d: %v", user.UserID, err)
+		return
+	}
+	user.PinHash = pinHash
+}
+
+func pinErrorResponse(c *gin.Context, status *models.PinStatus) {
+	if status.Locked {
+		c.JSON(http.StatusLocked, models.APIResponse{
+			Success: false,
+			Error:   "PIN is locked, please try again later",
+			Data:    status,
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, models.APIResponse{
+		Success: false,
+		Error:   "Invalid PIN",
+		Data:    status,
+	})
+}
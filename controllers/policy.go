@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"backend-ewallet/models"
+	"backend-ewallet/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkPassword adds the password policy violations, judged against the
+// user's own email, phone and name, under field.
+func checkPassword(errs models.FieldErrors, field string, password string, email string, phone string, fullName string) {
+	errs.Add(field, utils.DefaultPasswordPolicy().Validate(password, email, phone, fullName)...)
+}
+
+func checkPin(errs models.FieldErrors, field string, pin string, phone string) {
+	errs.Add(field, utils.ValidatePin(pin, phone)...)
+}
+
+// rejectWeakCredentials answers with the field-level errors, if any, and
+// reports whether it did.
+func rejectWeakCredentials(c *gin.Context, errs models.FieldErrors) bool {
+	if len(errs) == 0 {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, models.APIResponse{
+		Success: false,
+		Error:   "Credentials do not meet the security policy",
+		Data:    errs,
+	})
+	return true
+}
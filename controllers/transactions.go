@@ -5,20 +5,29 @@ import (
 	"backend-ewallet/utils"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
 )
 
+// Transfers cost 1% on top of the amount. Fees are rounded half up to whole
+// minor units.
+var transferFeePercentage = models.MustParsePercentage("1")
+
+const feeRounding = models.RoundHalfUp
+
+const maxStatementRange = 366 * 24 * time.Hour
+
 type TransactionController struct {
-	userRepo        *models.UserRepository
-	transactionRepo *models.TransactionRepository
+	userRepo        models.UserStore
+	transactionRepo models.TransactionStore
 }
 
-func NewTransactionController() *TransactionController {
+func NewTransactionController(userRepo models.UserStore, transactionRepo models.TransactionStore) *TransactionController {
 	return &TransactionController{
-		userRepo:        models.NewUserRepository(),
-		transactionRepo: models.NewTransactionRepository(),
+		userRepo:        userRepo,
+		transactionRepo: transactionRepo,
 	}
 }
 
@@ -41,6 +50,14 @@ func (tc *TransactionController) Transfer(c *gin.Context) {
 		return
 	}
 
+	if err := req.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	sender, err := tc.userRepo.GetUserByID(userID.(int))
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.APIResponse{
@@ -50,13 +67,18 @@ func (tc *TransactionController) Transfer(c *gin.Context) {
 		return
 	}
 
-	if !utils.CheckPasswordHash(req.Pin, sender.PinHash) {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+	validPin, pinStatus, err := verifyPin(tc.userRepo, sender, req.Pin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Message: "Invalid PIN",
+			Message: "Failed to verify PIN",
 		})
 		return
 	}
+	if !validPin {
+		pinErrorResponse(c, pinStatus)
+		return
+	}
 
 	receiver, err := tc.userRepo.GetUserByPhone(req.ReceiverPhone)
 	if err != nil {
@@ -80,7 +102,7 @@ func (tc *TransactionController) Transfer(c *gin.Context) {
 		return
 	}
 
-	fee := req.Amount * 0.01
+	fee := req.Amount.Percent(transferFeePercentage, feeRounding)
 	totalAmount := req.Amount + fee
 
 	if sender.Balance < totalAmount {
@@ -157,6 +179,66 @@ func (tc *TransactionController) GetTransactionHistory(c *gin.Context) {
 	})
 }
 
+// GetStatement returns the running balance between ?from and ?to
+// (YYYY-MM-DD, both inclusive), by default the current month so far.
+func (tc *TransactionController) GetStatement(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	for param, date := range map[string]*time.Time{"from": &from, "to": &to} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid date range",
+				Error:   param + " must be a date in YYYY-MM-DD format",
+			})
+			return
+		}
+		*date = parsed
+	}
+
+	// The statement covers the whole of the last day
+	end := to.AddDate(0, 0, 1)
+	if to.Before(from) || end.Sub(from) > maxStatementRange {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid date range",
+			Error:   "from must not be after to, and the range may span at most 366 days",
+		})
+		return
+	}
+
+	statement, err := tc.transactionRepo.GetStatement(userID.(int), from, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to get statement",
+		})
+		return
+	}
+	statement.To = to
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Statement retrieved successfully",
+		Data:    statement,
+	})
+}
+
 func (tc *TransactionController) GetBalance(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -230,12 +312,13 @@ func (tc *TransactionController) Topup(c *gin.Context) {
 		return
 	}
 
-	fee := req.Amount * paymentMethod.FeePercentage / 100
+	fee := req.Amount.Percent(paymentMethod.FeePercentage, feeRounding)
 
 	transaction := &models.Transaction{
 		ReceiverID:      &user.UserID,
 		TransactionType: "topup",
 		PaymentMethodID: &req.PaymentMethodID,
+		MethodVersionID: &paymentMethod.VersionID,
 		Amount:          req.Amount,
 		Fee:             fee,
 		Description:     "Top up via " + paymentMethod.MethodName,
@@ -243,33 +326,22 @@ func (tc *TransactionController) Topup(c *gin.Context) {
 		Status:          "completed",
 	}
 
-	if err := tc.transactionRepo.CreateTransaction(transaction); err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success: false,
-			Error:   "Failed to create transaction",
-		})
-		return
-	}
-
-	// Update user balance
-	newBalance := user.Balance + req.Amount
-	if err := tc.userRepo.UpdateUserBalance(user.UserID, newBalance); err != nil {
+	response, err := tc.transactionRepo.ProcessTopup(transaction)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "User not found",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success: false,
-			Error:   "Failed to update balance",
+			Error:   "Failed to process top up",
 		})
 		return
 	}
-
-	response := models.TransactionResponse{
-		TransactionID:   transaction.TransactionID,
-		TransactionType: transaction.TransactionType,
-		Amount:          req.Amount,
-		Fee:             fee,
-		ReferenceNumber: transaction.ReferenceNumber,
-		Status:          "COMPLETED",
-		NewBalance:      newBalance,
-	}
+	response.Status = "COMPLETED"
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"backend-ewallet/middlewares"
+	"backend-ewallet/models"
+	"backend-ewallet/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testPin = "123456"
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	dir, err := os.MkdirTemp("", "ewallet-keys")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("JWT_KEY_DIR", dir)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// transferServer wires the transfer route the way routers.transactionRouter
+// does, with every store backed by one MemoryStore.
+func transferServer(store *models.MemoryStore) *gin.Engine {
+	r := gin.New()
+	r.Use(middlewares.AuthMiddleware(store, store, store))
+	r.POST("/transactions/transfer", middlewares.RequireVerifiedAccount(), NewTransactionController(store, store).Transfer)
+	return r
+}
+
+func createTestUser(t *testing.T, store *models.MemoryStore, email string, phone string, balance models.Money) *models.User {
+	t.Helper()
+
+	pinHash, err := utils.HashPassword(testPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{
+		Email:              email,
+		Phone:              phone,
+		FullName:           email,
+		PinHash:            pinHash,
+		Balance:            balance,
+		RegistrationStatus: "completed",
+		IsActive:           true,
+		Role:               models.RoleUser,
+	}
+	if err := store.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// login opens a session for the user and returns its access token.
+func login(t *testing.T, store *models.MemoryStore, user *models.User) (string, int) {
+	t.Helper()
+
+	session := &models.Session{UserID: user.UserID, CreatedAt: time.Now(), LastSeenAt: time.Now()}
+	if err := store.CreateSession(session); err != nil {
+		t.Fatal(err)
+	}
+	token, err := utils.GenerateToken(utils.AccessClaims{
+		UserID:      user.UserID,
+		SessionID:   session.SessionID,
+		Role:        user.Role,
+		Permissions: models.PermissionsForRole(user.Role),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, session.SessionID
+}
+
+func postTransfer(r *gin.Engine, token string, body string) (int, models.APIResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/transactions/transfer", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var res models.APIResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return w.Code, res
+}
+
+func TestTransfer(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		revokeSession   bool
+		wantStatus      int
+		wantMessage     string
+		senderBalance   models.Money
+		receiverBalance models.Money
+	}{
+		{
+			name:            "moves the amount and the fee",
+			body:            `{"receiver_phone": "0812-0000-0002", "amount": "100.50", "pin": "123456"}`,
+			wantStatus:      http.StatusOK,
+			wantMessage:     "Transfer successful",
+			senderBalance:   100000 - 10050 - 101,
+			receiverBalance: 10050,
+		},
+		{
+			name:            "insufficient balance once the fee is added",
+			body:            `{"receiver_phone": "+6281200000002", "amount": "1000", "pin": "123456"}`,
+			wantStatus:      http.StatusBadRequest,
+			wantMessage:     "Insufficient balance",
+			senderBalance:   100000,
+			receiverBalance: 0,
+		},
+		{
+			name:            "unknown receiver",
+			body:            `{"receiver_phone": "+6281299999999", "amount": "10", "pin": "123456"}`,
+			wantStatus:      http.StatusNotFound,
+			wantMessage:     "Receiver not found",
+			senderBalance:   100000,
+			receiverBalance: 0,
+		},
+		{
+			name:            "too many decimal places",
+			body:            `{"receiver_phone": "+6281200000002", "amount": "10.505", "pin": "123456"}`,
+			wantStatus:      http.StatusBadRequest,
+			senderBalance:   100000,
+			receiverBalance: 0,
+		},
+		{
+			name:            "revoked session",
+			body:            `{"receiver_phone": "+6281200000002", "amount": "10", "pin": "123456"}`,
+			revokeSession:   true,
+			wantStatus:      http.StatusUnauthorized,
+			wantMessage:     "Session Expired!",
+			senderBalance:   100000,
+			receiverBalance: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := models.NewMemoryStore()
+			sender := createTestUser(t, store, "sender@example.com", "+6281200000001", 100000)
+			receiver := createTestUser(t, store, "receiver@example.com", "+6281200000002", 0)
+			token, sessionID := login(t, store, sender)
+			if tt.revokeSession {
+				if err := store.RevokeSession(sessionID, sender.UserID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			status, res := postTransfer(transferServer(store), token, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%+v)", status, tt.wantStatus, res)
+			}
+			if tt.wantMessage != "" && res.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", res.Message, tt.wantMessage)
+			}
+
+			if got, _ := store.GetUserByID(sender.UserID); got.Balance != tt.senderBalance {
+				t.Errorf("sender balance = %s, want %s", got.Balance, tt.senderBalance)
+			}
+			if got, _ := store.GetUserByID(receiver.UserID); got.Balance != tt.receiverBalance {
+				t.Errorf("receiver balance = %s, want %s", got.Balance, tt.receiverBalance)
+			}
+		})
+	}
+}
+
+func TestTransferRejectsTokensAfterLogoutAll(t *testing.T) {
+	store := models.NewMemoryStore()
+	sender := createTestUser(t, store, "sender@example.com", "+6281200000001", 100000)
+	createTestUser(t, store, "receiver@example.com", "+6281200000002", 0)
+	token, _ := login(t, store, sender)
+
+	if err := store.RevokeAllUserSessions(sender.UserID); err != nil {
+		t.Fatal(err)
+	}
+
+	status, res := postTransfer(transferServer(store), token, `{"receiver_phone": "+6281200000002", "amount": "10", "pin": "123456"}`)
+	if status != http.StatusUnauthorized || res.Message != "Token Invalid!" {
+		t.Fatalf("got %d %q, want 401 \"Token Invalid!\"", status, res.Message)
+	}
+}
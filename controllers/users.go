@@ -0,0 +1,446 @@
+package controllers
+
+import (
+	"backend-ewallet/models"
+	"backend-ewallet/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UserController struct {
+	userRepo         *models.UserRepository
+	otpRepo          *models.OTPRepository
+	verificationRepo *models.EmailVerificationRepository
+	sessionRepo      *models.SessionRepository
+	transactionRepo  *models.TransactionRepository
+	contactRepo      *models.ContactRepository
+	loginFailureRepo *models.LoginFailureRepository
+	notifier         utils.Notifier
+	smsSender        utils.SMSSender
+}
+
+func NewUserController(db *pgxpool.Pool) *UserController {
+	return &UserController{
+		userRepo:         models.NewUserRepository(db),
+		otpRepo:          models.NewOTPRepository(db),
+		verificationRepo: models.NewEmailVerificationRepository(db),
+		sessionRepo:      models.NewSessionRepository(db),
+		transactionRepo:  models.NewTransactionRepository(db),
+		contactRepo:      models.NewContactRepository(db),
+		loginFailureRepo: models.NewLoginFailureRepository(db),
+		notifier:         utils.NewNotifier(),
+		smsSender:        utils.NewSMSSender(),
+	}
+}
+
+func (uc *UserController) GetProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID.(int))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success: false,
+				Error:   "User not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	// Remove sensitive data
+	user.PasswordHash = ""
+	user.PinHash = ""
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Profile retrieved successfully",
+		Data:    user,
+	})
+}
+
+func (uc *UserController) UpdateProfile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := req.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	emailChanged := req.Email != nil && *req.Email != user.Email
+	phoneChanged := req.Phone != nil && *req.Phone != user.Phone
+
+	// Check if email already exists
+	if emailChanged {
+		existingUser, _ := uc.userRepo.GetUserByEmail(*req.Email)
+		if existingUser != nil {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Error:   "User with this email already exists",
+			})
+			return
+		}
+		user.Email = *req.Email
+		// A new address has to be verified again before money can move
+		if user.RegistrationStatus == "completed" {
+			user.RegistrationStatus = "pending"
+		}
+	}
+
+	// Check if phone already exists
+	if phoneChanged {
+		existingUser, _ := uc.userRepo.GetUserByPhone(*req.Phone)
+		if existingUser != nil {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Error:   "User with this phone number already exists",
+			})
+			return
+		}
+		user.Phone = *req.Phone
+		user.PhoneVerifiedAt = nil
+	}
+
+	if req.FullName != nil {
+		user.FullName = *req.FullName
+	}
+
+	if err := uc.userRepo.UpdateProfile(user); err != nil {
+		if models.IsUniqueViolation(err) {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success: false,
+				Error:   "Email or phone number is already in use",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to update profile",
+		})
+		return
+	}
+
+	// The user can ask for another email or code later, so a delivery failure is not fatal
+	if emailChanged {
+		if err := sendVerificationEmail(uc.verificationRepo, uc.notifier, user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.UserID, err)
+		}
+	}
+	if phoneChanged {
+		if err := sendOTP(uc.otpRepo, uc.smsSender, user, models.OTPPurposePhoneVerification); err != nil {
+			log.Printf("Failed to send phone verification code to user %d: %v", user.UserID, err)
+		}
+	}
+
+	// Remove sensitive data
+	user.PasswordHash = ""
+	user.PinHash = ""
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Profile updated successfully",
+		Data:    user,
+	})
+}
+
+func (uc *UserController) ChangePassword(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	if !confirmPassword(c, uc.loginFailureRepo, uc.notifier, user, req.CurrentPassword) {
+		return
+	}
+
+	policyErrors := models.FieldErrors{}
+	checkPassword(policyErrors, "new_password", req.NewPassword, user.Email, user.Phone, user.FullName)
+	if rejectWeakCredentials(c, policyErrors) {
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to hash password",
+		})
+		return
+	}
+
+	if err := uc.userRepo.UpdatePassword(user.UserID, passwordHash); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to update password",
+		})
+		return
+	}
+
+	// Keep the current device signed in, end every other session
+	if err := uc.sessionRepo.RevokeOtherSessions(user.UserID, c.GetInt("session_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to revoke other sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Password changed successfully",
+	})
+}
+
+func (uc *UserController) ChangePin(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	var req models.ChangePinRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	policyErrors := models.FieldErrors{}
+	checkPin(policyErrors, "new_pin", req.NewPin, user.Phone)
+	if rejectWeakCredentials(c, policyErrors) {
+		return
+	}
+
+	validPin, pinStatus, err := verifyPin(uc.userRepo, user, req.OldPin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to verify PIN",
+		})
+		return
+	}
+	if !validPin {
+		pinErrorResponse(c, pinStatus)
+		return
+	}
+
+	pinHash, err := utils.HashPassword(req.NewPin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to hash PIN",
+		})
+		return
+	}
+
+	if err := uc.userRepo.UpdatePin(user.UserID, pinHash); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to update PIN",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "PIN changed successfully",
+	})
+}
+
+func (uc *UserController) RequestPinReset(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	var req models.RequestPinResetRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	if !confirmPassword(c, uc.loginFailureRepo, uc.notifier, user, req.Password) {
+		return
+	}
+
+	if err := sendOTP(uc.otpRepo, uc.smsSender, user, models.OTPPurposePinReset); err != nil {
+		if err == errOTPThrottled {
+			c.JSON(http.StatusTooManyRequests, models.APIResponse{
+				Success: false,
+				Error:   "Please wait before requesting another code",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to send verification code",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Verification code sent to your phone",
+	})
+}
+
+func (uc *UserController) ResetPin(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success: false,
+			Error:   "User not authenticated",
+		})
+		return
+	}
+
+	var req models.ResetPinRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	user, err := uc.userRepo.GetUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+		return
+	}
+
+	if !confirmPassword(c, uc.loginFailureRepo, uc.notifier, user, req.Password) {
+		return
+	}
+
+	policyErrors := models.FieldErrors{}
+	checkPin(policyErrors, "new_pin", req.NewPin, user.Phone)
+	if rejectWeakCredentials(c, policyErrors) {
+		return
+	}
+
+	if err := uc.otpRepo.VerifyOTP(user.UserID, models.OTPPurposePinReset, req.Code); err != nil {
+		otpErrorResponse(c, err)
+		return
+	}
+
+	pinHash, err := utils.HashPassword(req.NewPin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to hash PIN",
+		})
+		return
+	}
+
+	if err := uc.userRepo.UpdatePin(user.UserID, pinHash); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Error:   "Failed to update PIN",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "PIN reset successfully",
+	})
+}
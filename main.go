@@ -2,8 +2,15 @@ package main
 
 import (
 	"backend-ewallet/routers"
+	"backend-ewallet/utils"
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -14,16 +21,54 @@ func main() {
 		log.Println("No .env file found")
 	}
 
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
+		return
+	}
+
+	if err := utils.StartKeyRotation(); err != nil {
+		log.Fatal("Failed to load signing keys:", err)
+	}
+
+	db, err := utils.NewDBPool()
+	if err != nil {
+		log.Fatal("Failed to connect to the database:", err)
+	}
+	defer db.Close()
+
 	r := gin.Default()
-	routers.CombineRouters(r)
+	routers.CombineRouters(r, db)
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	// Let in-flight requests finish before the pool goes away. A server that
+	// fails to start is reported here, on the main goroutine, so the pool is
+	// closed before exiting.
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-quit:
+	case err := <-serverErr:
+		db.Close()
 		log.Fatal("Failed to start server:", err)
 	}
+
+	log.Println("Shutting down server")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Server forced to shut down:", err)
+	}
 }
@@ -2,17 +2,16 @@ package middlewares
 
 import (
 	"backend-ewallet/models"
+	"backend-ewallet/utils"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(userRepo models.UserStore, sessionRepo models.SessionStore, revokedTokenRepo models.RevocationStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if r := recover(); r != nil {
@@ -22,8 +21,6 @@ func AuthMiddleware() gin.HandlerFunc {
 				})
 			}
 		}()
-		godotenv.Load()
-		secretKey := os.Getenv("APP_SECRET")
 		token := strings.Split(c.GetHeader("Authorization"), "Bearer ")
 
 		if len(token) < 2 {
@@ -36,9 +33,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		tokenString := strings.TrimSpace(token[1])
-		rawToken, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-			return []byte(secretKey), nil
-		})
+		rawToken, err := jwt.Parse(tokenString, utils.VerificationKey, jwt.WithValidMethods(utils.SigningMethods()))
 
 		if err != nil {
 			if strings.Contains(err.Error(), "expired") {
@@ -57,10 +52,71 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		userIdFloat := rawToken.Claims.(jwt.MapClaims)["user_id"]
+		claims := rawToken.Claims.(jwt.MapClaims)
+		userIdFloat := claims["user_id"]
 		userId := int(userIdFloat.(float64))
 
+		// Tokens minted before the last password reset or role change carry a stale version
+		tokenVersion, _ := claims["ver"].(float64)
+		role, _ := claims["role"].(string)
+		user, err := userRepo.GetUserByID(userId)
+		if err != nil || user.TokenVersion != int(tokenVersion) || user.Role != role {
+			c.JSON(http.StatusUnauthorized, models.APIResponse{
+				Success: false,
+				Message: "Token Invalid!",
+			})
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if user.RegistrationStatus == "suspended" {
+			c.JSON(http.StatusForbidden, models.APIResponse{
+				Success: false,
+				Message: "Account Suspended!",
+			})
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		exp, _ := claims.GetExpirationTime()
+		if exp == nil {
+			c.JSON(http.StatusUnauthorized, models.APIResponse{
+				Success: false,
+				Message: "Token Invalid!",
+			})
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		jti, _ := claims["jti"].(string)
+		revoked, err := revokedTokenRepo.IsRevoked(jti, exp.Time)
+		if jti == "" || err != nil || revoked {
+			c.JSON(http.StatusUnauthorized, models.APIResponse{
+				Success: false,
+				Message: "Token Revoked!",
+			})
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		sessionIdFloat, _ := claims["sid"].(float64)
+		sessionId := int(sessionIdFloat)
+		if err := sessionRepo.TouchSession(sessionId, userId); err != nil {
+			c.JSON(http.StatusUnauthorized, models.APIResponse{
+				Success: false,
+				Message: "Session Expired!",
+			})
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Set("token_exp", exp.Time)
 		c.Set("user_id", userId)
+		c.Set("jti", jti)
+		c.Set("session_id", sessionId)
+		c.Set("registration_status", user.RegistrationStatus)
+		c.Set("role", role)
+		c.Set("permissions", models.PermissionsForRole(role))
 		c.Next()
 	}
 }
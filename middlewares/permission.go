@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"backend-ewallet/models"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission must run after AuthMiddleware. It lets the request through
+// only when the token grants every listed permission.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetStringSlice("permissions")
+		for _, permission := range permissions {
+			if !slices.Contains(granted, permission) {
+				c.JSON(http.StatusForbidden, models.APIResponse{
+					Success: false,
+					Message: "Forbidden!",
+				})
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+		}
+		c.Next()
+	}
+}
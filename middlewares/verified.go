@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"backend-ewallet/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedAccount must run after AuthMiddleware. It blocks accounts whose
+// registration is still pending.
+func RequireVerifiedAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("registration_status") != "completed" {
+			c.JSON(http.StatusForbidden, models.APIResponse{
+				Success: false,
+				Message: "Account is not verified!",
+			})
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS users (
+    user_id SERIAL PRIMARY KEY,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    phone VARCHAR(20) UNIQUE NOT NULL,
+    full_name VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    pin_hash VARCHAR(255) NOT NULL,
+    balance DECIMAL(15, 2) DEFAULT 0.00 CHECK (balance >= 0),
+    registration_status VARCHAR(50) DEFAULT 'pending' CHECK (
+        registration_status IN (
+            'pending',
+            'completed',
+            'suspended'
+        )
+    ),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_login TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN DEFAULT TRUE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS contacts (
+    contact_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    contact_user_id INTEGER REFERENCES users (user_id) ON DELETE CASCADE,
+    contact_name VARCHAR(100) NOT NULL,
+    contact_phone VARCHAR(20) NOT NULL,
+    is_favorite BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
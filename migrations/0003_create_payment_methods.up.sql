@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS payment_methods (
+    method_id SERIAL PRIMARY KEY,
+    method_name VARCHAR(255) NOT NULL,
+    method_type VARCHAR(100) NOT NULL CHECK (
+        method_type IN (
+            'bank_transfer',
+            'e_wallet',
+            'retail'
+        )
+    ),
+    is_active BOOLEAN DEFAULT TRUE,
+    min_amount DECIMAL(15, 2) DEFAULT 10000.00,
+    max_amount DECIMAL(15, 2) DEFAULT 10000000.00,
+    fee_percentage DECIMAL(5, 4) DEFAULT 0.0000
+);
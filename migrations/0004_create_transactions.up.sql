@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS transactions (
+    transaction_id SERIAL PRIMARY KEY,
+    sender_id INTEGER REFERENCES users (user_id) ON DELETE SET NULL,
+    receiver_id INTEGER REFERENCES users (user_id) ON DELETE SET NULL,
+    method_id INTEGER REFERENCES payment_methods (method_id),
+    transaction_type VARCHAR(50) NOT NULL CHECK (
+        transaction_type IN ('transfer', 'topup')
+    ),
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    fee DECIMAL(15, 2) DEFAULT 0.00 CHECK (fee >= 0),
+    description TEXT,
+    reference_number VARCHAR(255) UNIQUE NOT NULL,
+    status VARCHAR(50) DEFAULT 'pending' CHECK (
+        status IN (
+            'pending',
+            'processing',
+            'completed',
+            'failed',
+            'cancelled'
+        )
+    ),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS transaction_history (
+    history_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    transaction_id INTEGER NOT NULL REFERENCES transactions (transaction_id) ON DELETE CASCADE,
+    transaction_summary TEXT NOT NULL,
+    balance_before DECIMAL(15, 2) NOT NULL,
+    balance_after DECIMAL(15, 2) NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS otp_codes;
+DROP TABLE IF EXISTS email_verifications;
+DROP TABLE IF EXISTS password_resets;
@@ -0,0 +1,11 @@
+CREATE TABLE audit_logs (
+    audit_id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL REFERENCES users (user_id),
+    action VARCHAR(100) NOT NULL,
+    target_user_id INTEGER REFERENCES users (user_id) ON DELETE SET NULL,
+    details JSONB,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_logs_target ON audit_logs (target_user_id, created_at);
@@ -0,0 +1,88 @@
+CREATE TABLE ledger_accounts (
+    account_id SERIAL PRIMARY KEY,
+    account_type VARCHAR(20) NOT NULL CHECK (
+        account_type IN ('user', 'system')
+    ),
+    user_id INTEGER UNIQUE REFERENCES users (user_id),
+    code VARCHAR(50) UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (
+        (
+            account_type = 'user'
+            AND user_id IS NOT NULL
+            AND code IS NULL
+        )
+        OR (
+            account_type = 'system'
+            AND user_id IS NULL
+            AND code IS NOT NULL
+        )
+    )
+);
+
+CREATE TABLE journal_entries (
+    entry_id SERIAL PRIMARY KEY,
+    transaction_id INTEGER REFERENCES transactions (transaction_id),
+    description TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_journal_entries_transaction ON journal_entries (transaction_id);
+
+CREATE TABLE postings (
+    posting_id SERIAL PRIMARY KEY,
+    entry_id INTEGER NOT NULL REFERENCES journal_entries (entry_id),
+    account_id INTEGER NOT NULL REFERENCES ledger_accounts (account_id),
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount <> 0)
+);
+
+CREATE INDEX idx_postings_account ON postings (account_id);
+
+CREATE INDEX idx_postings_entry ON postings (entry_id);
+
+-- Every journal entry has to sum to zero once its transaction commits.
+CREATE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM postings WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'journal entry % does not balance', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER postings_balanced
+AFTER INSERT OR UPDATE ON postings
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+INSERT INTO
+    ledger_accounts (account_type, code)
+VALUES ('system', 'fee_revenue'),
+    ('system', 'topup_clearing'),
+    ('system', 'opening_balance');
+
+INSERT INTO
+    ledger_accounts (account_type, user_id)
+SELECT 'user', user_id
+FROM users;
+
+-- Balances that existed before the ledger are booked against opening_balance.
+DO $$
+DECLARE
+    holder RECORD;
+    opening INTEGER;
+    entry INTEGER;
+BEGIN
+    SELECT account_id INTO opening FROM ledger_accounts WHERE code = 'opening_balance';
+    FOR holder IN
+        SELECT a.account_id, u.balance
+        FROM users u JOIN ledger_accounts a ON a.user_id = u.user_id
+        WHERE u.balance <> 0
+    LOOP
+        INSERT INTO journal_entries (description) VALUES ('Opening balance')
+        RETURNING entry_id INTO entry;
+        INSERT INTO postings (entry_id, account_id, amount)
+        VALUES (entry, holder.account_id, holder.balance), (entry, opening, -holder.balance);
+    END LOOP;
+END;
+$$;
@@ -0,0 +1,22 @@
+ALTER TABLE users
+ADD COLUMN suspended_from VARCHAR(50) CHECK (
+    suspended_from IN ('pending', 'completed')
+);
+
+-- Users suspended before the column existed get back the status recorded by
+-- their last suspension, or pending when there is none, so reactivation can
+-- never skip email verification.
+UPDATE users u
+SET suspended_from = COALESCE(
+    (
+        SELECT a.details ->> 'previous_status'
+        FROM audit_logs a
+        WHERE a.target_user_id = u.user_id
+            AND a.action = 'users.suspend'
+            AND a.details ->> 'previous_status' IN ('pending', 'completed')
+        ORDER BY a.created_at DESC
+        LIMIT 1
+    ),
+    'pending'
+)
+WHERE u.registration_status = 'suspended';
@@ -0,0 +1,26 @@
+-- Deleting a posting, or moving it to another entry, can unbalance an entry
+-- just like inserting one, so the entries a row leaves are checked too.
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+DECLARE
+    checked INTEGER;
+BEGIN
+    FOREACH checked IN ARRAY CASE TG_OP
+        WHEN 'INSERT' THEN ARRAY[NEW.entry_id]
+        WHEN 'DELETE' THEN ARRAY[OLD.entry_id]
+        ELSE ARRAY[OLD.entry_id, NEW.entry_id]
+    END
+    LOOP
+        IF COALESCE((SELECT SUM(amount) FROM postings WHERE entry_id = checked), 0) <> 0 THEN
+            RAISE EXCEPTION 'journal entry % does not balance', checked;
+        END IF;
+    END LOOP;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER postings_balanced ON postings;
+
+CREATE CONSTRAINT TRIGGER postings_balanced
+AFTER INSERT OR UPDATE OR DELETE ON postings
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
@@ -0,0 +1,3 @@
+-- The names removed from the summaries are not restored, statements resolve
+-- the counterparty either way.
+SELECT 1;
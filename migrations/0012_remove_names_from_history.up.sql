@@ -0,0 +1,12 @@
+-- Transfer summaries used to name the counterparty, which kept a closed
+-- account's name in the other user's history. Statements now look the
+-- counterparty up when they are read, so the summaries are rebuilt from the
+-- transaction without it.
+UPDATE transaction_history h
+SET transaction_summary = CASE
+        WHEN h.balance_after < h.balance_before THEN 'Transfer sent'
+        ELSE 'Transfer received'
+    END || COALESCE(': ' || NULLIF(t.description, ''), '')
+FROM transactions t
+WHERE t.transaction_id = h.transaction_id
+    AND t.transaction_type = 'transfer';
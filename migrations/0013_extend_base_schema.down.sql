@@ -0,0 +1,9 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS method_version_id;
+DROP TABLE IF EXISTS payment_method_versions;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS closed_at,
+    DROP COLUMN IF EXISTS role,
+    DROP COLUMN IF EXISTS pin_locked_until,
+    DROP COLUMN IF EXISTS pin_failed_attempts,
+    DROP COLUMN IF EXISTS phone_verified_at,
+    DROP COLUMN IF EXISTS token_version;
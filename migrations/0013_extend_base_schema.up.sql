@@ -0,0 +1,57 @@
+-- The columns added to users and transactions after the released base.sql.
+-- 0001-0004 create those tables exactly as base.sql did, so databases set
+-- up from it and fresh ones both get here through the same ALTERs.
+ALTER TABLE users
+    ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN phone_verified_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN pin_failed_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN pin_locked_until TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user' CHECK (
+        role IN ('user', 'support', 'admin')
+    ),
+    ADD COLUMN closed_at TIMESTAMP WITH TIME ZONE;
+-- The defaults backfill existing users: token version 0, no PIN failures and
+-- the user role. Nobody was phone verified, locked or closed under base.sql.
+
+CREATE TABLE payment_method_versions (
+    version_id SERIAL PRIMARY KEY,
+    method_id INTEGER NOT NULL REFERENCES payment_methods (method_id) ON DELETE CASCADE,
+    method_name VARCHAR(255) NOT NULL,
+    method_type VARCHAR(100) NOT NULL,
+    is_active BOOLEAN NOT NULL,
+    min_amount DECIMAL(15, 2) NOT NULL,
+    max_amount DECIMAL(15, 2) NOT NULL,
+    fee_percentage DECIMAL(5, 4) NOT NULL,
+    created_by INTEGER REFERENCES users (user_id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payment_method_versions_method ON payment_method_versions (method_id, version_id);
+
+-- Every payment method needs a version, the current one is read as its
+-- latest. Existing methods start from their present configuration.
+INSERT INTO
+    payment_method_versions (
+        method_id,
+        method_name,
+        method_type,
+        is_active,
+        min_amount,
+        max_amount,
+        fee_percentage
+    )
+SELECT
+    method_id,
+    method_name,
+    method_type,
+    COALESCE(is_active, TRUE),
+    COALESCE(min_amount, 10000.00),
+    COALESCE(max_amount, 10000000.00),
+    COALESCE(fee_percentage, 0.0000)
+FROM payment_methods
+ORDER BY method_id;
+
+-- Top-ups recorded before versioning keep a NULL version: which fee the
+-- method charged at the time is not known.
+ALTER TABLE transactions
+ADD COLUMN method_version_id INTEGER REFERENCES payment_method_versions (version_id);
@@ -0,0 +1,305 @@
+// Package migrations holds the versioned database schema. Every change is a
+// pair of files, NNNN_name.up.sql and NNNN_name.down.sql, embedded in the
+// binary and applied in version order. Applied versions are recorded in
+// schema_migrations together with a checksum of their up script, so an
+// edited migration is detected instead of silently diverging.
+//
+// Migrations 0001-0004 create users, contacts, payment_methods, transactions
+// and transaction_history exactly as the released base.sql did, with CREATE
+// ... IF NOT EXISTS, so that a database set up from base.sql is adopted by
+// the first `migrate up`. Every later change to those tables is an ALTER in
+// its own migration. Later migrations don't use IF NOT EXISTS, a failing
+// statement is better than a skipped one.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed *.sql
+var migrationFiles embed.FS
+
+//go:embed seeds/*.sql
+var seedFiles embed.FS
+
+// lockKey is the pg_advisory_lock key that keeps two migrators apart.
+const lockKey = 7_301_994_202
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus is a migration as seen from the database. State is
+// "applied", "pending", "modified" (applied, but the embedded up script no
+// longer matches the checksum) or "missing" (applied, but unknown to this
+// binary).
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+type appliedMigration struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Load reads the embedded migrations in version order.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := migrationFiles.ReadFile(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %q and %q", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		sum := sha256.Sum256([]byte(migration.Up))
+		migration.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies up to steps pending migrations, all of them when steps <= 0,
+// each in its own transaction. It refuses to run while an applied migration
+// has been modified.
+func Up(db *pgxpool.Pool, steps int) ([]Migration, error) {
+	var done []Migration
+	err := withLock(db, func(conn *pgxpool.Conn, migrations []Migration, applied map[int]appliedMigration) error {
+		for _, migration := range migrations {
+			if record, ok := applied[migration.Version]; ok && record.Checksum != migration.Checksum {
+				return fmt.Errorf("migration %d_%s was modified after it was applied", migration.Version, migration.Name)
+			}
+		}
+
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if steps > 0 && len(done) == steps {
+				break
+			}
+			err := runInTx(conn, migration.Up, func(tx pgx.Tx) error {
+				_, err := tx.Exec(context.Background(),
+					`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+					migration.Version, migration.Name, migration.Checksum, time.Now())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func Down(db *pgxpool.Pool, steps int) ([]Migration, error) {
+	var done []Migration
+	err := withLock(db, func(conn *pgxpool.Conn, migrations []Migration, applied map[int]appliedMigration) error {
+		for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			err := runInTx(conn, migration.Down, func(tx pgx.Tx) error {
+				_, err := tx.Exec(context.Background(),
+					`DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every migration known to the binary or the database.
+func Status(db *pgxpool.Pool) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := withLock(db, func(conn *pgxpool.Conn, migrations []Migration, applied map[int]appliedMigration) error {
+		known := make(map[int]bool)
+		for _, migration := range migrations {
+			known[migration.Version] = true
+			status := MigrationStatus{Version: migration.Version, Name: migration.Name, State: "pending"}
+			if record, ok := applied[migration.Version]; ok {
+				status.State = "applied"
+				if record.Checksum != migration.Checksum {
+					status.State = "modified"
+				}
+				status.AppliedAt = &record.AppliedAt
+			}
+			statuses = append(statuses, status)
+		}
+
+		for version, record := range applied {
+			if !known[version] {
+				statuses = append(statuses, MigrationStatus{
+					Version:   version,
+					Name:      record.Name,
+					State:     "missing",
+					AppliedAt: &record.AppliedAt,
+				})
+			}
+		}
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+		return nil
+	})
+	return statuses, err
+}
+
+// Seed runs the embedded seed scripts in name order. They are written to be
+// re-runnable and only insert rows that are not there yet.
+func Seed(db *pgxpool.Pool) ([]string, error) {
+	entries, err := fs.ReadDir(seedFiles, "seeds")
+	if err != nil {
+		return nil, err
+	}
+
+	var done []string
+	err = withLock(db, func(conn *pgxpool.Conn, migrations []Migration, applied map[int]appliedMigration) error {
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; !ok {
+				return fmt.Errorf("migration %d_%s is pending, run `migrate up` first", migration.Version, migration.Name)
+			}
+		}
+		for _, entry := range entries {
+			content, err := seedFiles.ReadFile("seeds/" + entry.Name())
+			if err != nil {
+				return err
+			}
+			if err := runInTx(conn, string(content), nil); err != nil {
+				return fmt.Errorf("seed %s: %w", entry.Name(), err)
+			}
+			done = append(done, entry.Name())
+		}
+		return nil
+	})
+	return done, err
+}
+
+// withLock holds the migration advisory lock on a dedicated connection while
+// fn runs, and hands it the embedded and the applied migrations.
+func withLock(db *pgxpool.Pool, fn func(conn *pgxpool.Conn, migrations []Migration, applied map[int]appliedMigration) error) error {
+	migrations, err := Load()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Acquire(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	_, err = conn.Exec(context.Background(), `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return err
+	}
+
+	rows, err := conn.Query(context.Background(),
+		`SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	records, err := pgx.CollectRows[appliedMigration](rows, pgx.RowToStructByName)
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[int]appliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return fn(conn, migrations, applied)
+}
+
+// runInTx executes script and then record in one transaction.
+func runInTx(conn *pgxpool.Conn, script string, record func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	if _, err := tx.Exec(context.Background(), script); err != nil {
+		return err
+	}
+	if record != nil {
+		if err := record(tx); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(context.Background())
+}
@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	createTable = regexp.MustCompile(`(?i)CREATE TABLE (?:IF NOT EXISTS )?(\w+)`)
+	references  = regexp.MustCompile(`(?i)REFERENCES (\w+)`)
+)
+
+func TestLoadOrdersVersions(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d_%s is at position %d, versions must be contiguous", migration.Version, migration.Name, i+1)
+		}
+		if migration.Checksum == "" {
+			t.Errorf("migration %d_%s has no checksum", migration.Version, migration.Name)
+		}
+	}
+}
+
+// Only the tables base.sql created may be created conditionally, anything
+// later has to fail loudly instead of being skipped on an adopted database.
+func TestOnlyBaseTablesAreCreatedIfNotExists(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range migrations {
+		if migration.Version > 4 && strings.Contains(strings.ToUpper(migration.Up), "IF NOT EXISTS") {
+			t.Errorf("migration %d_%s uses IF NOT EXISTS", migration.Version, migration.Name)
+		}
+	}
+}
+
+// A fresh database applies the up scripts one statement after the other, so
+// every foreign key must point at a table an earlier statement created.
+func TestReferencedTablesAreCreatedFirst(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := make(map[string]bool)
+	for _, migration := range migrations {
+		for _, statement := range strings.Split(migration.Up, ";") {
+			if match := createTable.FindStringSubmatch(statement); match != nil {
+				created[strings.ToLower(match[1])] = true
+			}
+			for _, match := range references.FindAllStringSubmatch(statement, -1) {
+				if !created[strings.ToLower(match[1])] {
+					t.Errorf("migration %d_%s references %s before it is created", migration.Version, migration.Name, match[1])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+INSERT INTO
+    payment_methods (
+        method_name,
+        method_type,
+        min_amount,
+        max_amount,
+        fee_percentage
+    )
+SELECT
+    v.method_name,
+    v.method_type,
+    v.min_amount,
+    v.max_amount,
+    v.fee_percentage
+FROM (
+        VALUES (
+                'Bank Transfer - BCA',
+                'bank_transfer',
+                10000.00,
+                10000000.00,
+                0.0000
+            ),
+            (
+                'Bank Transfer - Mandiri',
+                'bank_transfer',
+                10000.00,
+                10000000.00,
+                0.0000
+            ),
+            (
+                'Bank Transfer - BNI',
+                'bank_transfer',
+                10000.00,
+                10000000.00,
+                0.0000
+            ),
+            (
+                'Gopay',
+                'e_wallet',
+                10000.00,
+                2000000.00,
+                0.0250
+            ),
+            (
+                'Ovo',
+                'e_wallet',
+                10000.00,
+                2000000.00,
+                0.0150
+            ),
+            (
+                'Indomaret',
+                'retail',
+                10000.00,
+                1000000.00,
+                0.0200
+            ),
+            (
+                'Alfamart',
+                'retail',
+                10000.00,
+                1000000.00,
+                0.0200
+            )
+    ) AS v (
+        method_name,
+        method_type,
+        min_amount,
+        max_amount,
+        fee_percentage
+    )
+WHERE
+    NOT EXISTS (
+        SELECT 1
+        FROM payment_methods pm
+        WHERE
+            pm.method_name = v.method_name
+    );
+
+-- Methods inserted above get their first version here, the ones that
+-- existed when the versions table was created got it from the migration.
+INSERT INTO
+    payment_method_versions (
+        method_id,
+        method_name,
+        method_type,
+        is_active,
+        min_amount,
+        max_amount,
+        fee_percentage
+    )
+SELECT
+    pm.method_id,
+    pm.method_name,
+    pm.method_type,
+    pm.is_active,
+    pm.min_amount,
+    pm.max_amount,
+    pm.fee_percentage
+FROM payment_methods pm
+WHERE
+    NOT EXISTS (
+        SELECT 1
+        FROM payment_method_versions v
+        WHERE
+            v.method_id = pm.method_id
+    );
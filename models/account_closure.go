@@ -0,0 +1,91 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrAccountHasBalance             = errors.New("account still holds a balance")
+	ErrAccountHasPendingTransactions = errors.New("account has pending transactions")
+)
+
+// CloseAccount deactivates the user and strips personal data: email, phone
+// and name are replaced by placeholders, credentials, contacts, sessions and
+// verification records are removed, and the user's number is blanked in
+// other people's contact lists. Transactions and their history stay as they
+// are, they only reference the user by id.
+// The balance and pending transfers are re-checked under a row lock.
+func (r *UserRepository) CloseAccount(userID int) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	var balance Money
+	var email string
+	err = tx.QueryRow(context.Background(),
+		`SELECT balance, email FROM users WHERE user_id = $1 AND is_active = true FOR UPDATE`,
+		userID).Scan(&balance, &email)
+	if err != nil {
+		return err
+	}
+	if balance != 0 {
+		return ErrAccountHasBalance
+	}
+
+	var pending bool
+	err = tx.QueryRow(context.Background(), `
+		SELECT EXISTS (
+			SELECT 1 FROM transactions
+			WHERE (sender_id = $1 OR receiver_id = $1) AND status IN ('pending', 'processing')
+		)`, userID).Scan(&pending)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return ErrAccountHasPendingTransactions
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(context.Background(), `
+		UPDATE users SET
+			email = $1, phone = $2, full_name = 'Closed account',
+			password_hash = '', pin_hash = '',
+			phone_verified_at = NULL, pin_failed_attempts = 0, pin_locked_until = NULL,
+			is_active = false, token_version = token_version + 1,
+			closed_at = $3, updated_at = $3
+		WHERE user_id = $4`,
+		fmt.Sprintf("closed-%d@invalid", userID), fmt.Sprintf("closed-%d", userID), now, userID)
+	if err != nil {
+		return err
+	}
+
+	cleanup := []string{
+		`DELETE FROM contacts WHERE user_id = $1`,
+		`UPDATE contacts SET contact_phone = '' WHERE contact_user_id = $1`,
+		`DELETE FROM refresh_tokens WHERE user_id = $1`,
+		`DELETE FROM sessions WHERE user_id = $1`,
+		`DELETE FROM password_resets WHERE user_id = $1`,
+		`DELETE FROM email_verifications WHERE user_id = $1`,
+		`DELETE FROM otp_codes WHERE user_id = $1`,
+		`DELETE FROM mfa_challenges WHERE user_id = $1`,
+		`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_totp WHERE user_id = $1`,
+	}
+	for _, query := range cleanup {
+		if _, err := tx.Exec(context.Background(), query, userID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(context.Background(), `DELETE FROM login_failures WHERE email = $1`, email)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
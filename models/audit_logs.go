@@ -0,0 +1,85 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditLog struct {
+	AuditID      int             `json:"audit_id" db:"audit_id"`
+	ActorID      int             `json:"actor_id" db:"actor_id"`
+	Action       string          `json:"action" db:"action"`
+	TargetUserID *int            `json:"target_user_id" db:"target_user_id"`
+	Details      json.RawMessage `json:"details" db:"details"`
+	IPAddress    string          `json:"ip_address" db:"ip_address"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+}
+
+type AuditLogRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditLogRepository(db *pgxpool.Pool) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+const insertAuditLogQuery = `
+	INSERT INTO audit_logs (actor_id, action, target_user_id, details, ip_address, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING audit_id`
+
+func (r *AuditLogRepository) CreateAuditLog(log *AuditLog) error {
+	return r.db.QueryRow(context.Background(), insertAuditLogQuery,
+		log.ActorID,
+		log.Action,
+		log.TargetUserID,
+		log.Details,
+		log.IPAddress,
+		log.CreatedAt).
+		Scan(&log.AuditID)
+}
+
+// insertAuditLog writes the entry as part of tx, for actions whose audit
+// row must commit or roll back together with the change itself.
+func insertAuditLog(tx pgx.Tx, log *AuditLog) error {
+	return tx.QueryRow(context.Background(), insertAuditLogQuery,
+		log.ActorID,
+		log.Action,
+		log.TargetUserID,
+		log.Details,
+		log.IPAddress,
+		log.CreatedAt).
+		Scan(&log.AuditID)
+}
+
+// GetAuditLogs lists entries newest first, optionally only those about targetUserID.
+func (r *AuditLogRepository) GetAuditLogs(targetUserID *int, limit int, offset int) ([]AuditLog, int, error) {
+	var total int
+	err := r.db.QueryRow(context.Background(), `
+		SELECT COUNT(*) FROM audit_logs
+		WHERE $1::int IS NULL OR target_user_id = $1`, targetUserID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(context.Background(), `
+		SELECT audit_id, actor_id, action, target_user_id, details, ip_address, created_at
+		FROM audit_logs
+		WHERE $1::int IS NULL OR target_user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`, targetUserID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	logs, err := pgx.CollectRows[AuditLog](rows, pgx.RowToStructByName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ContactRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewContactRepository(db *pgxpool.Pool) *ContactRepository {
+	return &ContactRepository{db: db}
+}
+
+func (r *ContactRepository) GetContactsByUserID(userID int) ([]Contact, error) {
+	query := `
+		SELECT contact_id, user_id, contact_user_id, contact_name, contact_phone, is_favorite, created_at
+		FROM contacts
+		WHERE user_id = $1
+		ORDER BY contact_name`
+
+	rows, err := r.db.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows[Contact](rows, pgx.RowToStructByName)
+}
@@ -1,26 +1,172 @@
 package models
 
+import "backend-ewallet/utils"
+
 type RegisterRequest struct {
 	Email    string `form:"email" json:"email" binding:"required,email"`
 	Phone    string `form:"phone" json:"phone" binding:"required"`
 	FullName string `form:"full_name" json:"full_name" binding:"required"`
-	Password string `form:"password" json:"password" binding:"required,min=8"`
-	Pin      string `form:"pin" json:"pin" binding:"required,len=6"`
+	Password string `form:"password" json:"password" binding:"required"`
+	Pin      string `form:"pin" json:"pin" binding:"required"`
 }
 
 type LoginRequest struct {
-	Email    string `form:"email" json:"email" binding:"required,email"`
-	Password string `form:"password" json:"password" binding:"required"`
+	Email       string `form:"email" json:"email" binding:"required,email"`
+	Password    string `form:"password" json:"password" binding:"required"`
+	DeviceLabel string `form:"device_label" json:"device_label" binding:"max=100"`
 }
 
 type TransferRequest struct {
-	ReceiverPhone string  `form:"receiver_phone" json:"receiver_phone" binding:"required"`
-	Amount        float64 `form:"amount" json:"amount" binding:"required,gt=0"`
-	Description   string  `form:"description" json:"description"`
-	Pin           string  `form:"pin" json:"pin" binding:"required,len=6"`
+	ReceiverPhone string `form:"receiver_phone" json:"receiver_phone" binding:"required"`
+	Amount        Money  `form:"amount" json:"amount" binding:"required,gt=0"`
+	Description   string `form:"description" json:"description"`
+	Pin           string `form:"pin" json:"pin" binding:"required,len=6"`
 }
 
 type TopUpRequest struct {
-	Amount          float64 `form:"amount" json:"amount" binding:"required,gt=0"`
-	PaymentMethodID int     `form:"payment_method_id" json:"payment_method_id" binding:"required"`
+	Amount          Money `form:"amount" json:"amount" binding:"required,gt=0"`
+	PaymentMethodID int   `form:"payment_method_id" json:"payment_method_id" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `form:"email" json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `form:"token" json:"token" binding:"required"`
+	NewPassword string `form:"new_password" json:"new_password" binding:"required"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `form:"refresh_token" json:"refresh_token" binding:"required"`
+}
+
+type VerifyOTPRequest struct {
+	Code string `form:"code" json:"code" binding:"required,len=6,numeric"`
+}
+
+type RequestLoginOTPRequest struct {
+	Phone string `form:"phone" json:"phone" binding:"required"`
+}
+
+type OTPLoginRequest struct {
+	Phone       string `form:"phone" json:"phone" binding:"required"`
+	Code        string `form:"code" json:"code" binding:"required,len=6,numeric"`
+	DeviceLabel string `form:"device_label" json:"device_label" binding:"max=100"`
+}
+
+type DisableTOTPRequest struct {
+	Password     string `form:"password" json:"password" binding:"required"`
+	Code         string `form:"code" json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `form:"recovery_code" json:"recovery_code"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken     string `form:"mfa_token" json:"mfa_token" binding:"required"`
+	Code         string `form:"code" json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `form:"recovery_code" json:"recovery_code"`
+}
+
+type ChangePinRequest struct {
+	OldPin string `form:"old_pin" json:"old_pin" binding:"required,len=6"`
+	NewPin string `form:"new_pin" json:"new_pin" binding:"required,len=6,numeric,nefield=OldPin"`
+}
+
+type RequestPinResetRequest struct {
+	Password string `form:"password" json:"password" binding:"required"`
+}
+
+type ResetPinRequest struct {
+	Password string `form:"password" json:"password" binding:"required"`
+	Code     string `form:"code" json:"code" binding:"required,len=6,numeric"`
+	NewPin   string `form:"new_pin" json:"new_pin" binding:"required,len=6,numeric"`
+}
+
+type UpdateProfileRequest struct {
+	FullName *string `form:"full_name" json:"full_name" binding:"omitempty,min=1"`
+	Email    *string `form:"email" json:"email" binding:"omitempty,email"`
+	Phone    *string `form:"phone" json:"phone" binding:"omitempty,min=1"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `form:"current_password" json:"current_password" binding:"required"`
+	NewPassword     string `form:"new_password" json:"new_password" binding:"required"`
+}
+
+type CloseAccountRequest struct {
+	Pin string `form:"pin" json:"pin" binding:"required,len=6"`
+}
+
+type AdminActionRequest struct {
+	Reason string `form:"reason" json:"reason" binding:"required,max=255"`
+}
+
+type CreatePaymentMethodRequest struct {
+	MethodName string `form:"method_name" json:"method_name" binding:"required,max=255"`
+	MethodType string `form:"method_type" json:"method_type" binding:"required,oneof=bank_transfer e_wallet retail"`
+	IsActive   *bool  `form:"is_active" json:"is_active"`
+	MinAmount  Money  `form:"min_amount" json:"min_amount" binding:"required,gt=0"`
+	MaxAmount  Money  `form:"max_amount" json:"max_amount" binding:"required,gtfield=MinAmount"`
+	// Percentages are validated in their ten-thousandths, lt=100000 is below 10%
+	FeePercentage Percentage `form:"fee_percentage" json:"fee_percentage" binding:"gte=0,lt=100000"`
+}
+
+type UpdatePaymentMethodRequest struct {
+	MethodName    *string     `form:"method_name" json:"method_name" binding:"omitempty,min=1,max=255"`
+	MethodType    *string     `form:"method_type" json:"method_type" binding:"omitempty,oneof=bank_transfer e_wallet retail"`
+	MinAmount     *Money      `form:"min_amount" json:"min_amount" binding:"omitempty,gt=0"`
+	MaxAmount     *Money      `form:"max_amount" json:"max_amount" binding:"omitempty,gt=0"`
+	FeePercentage *Percentage `form:"fee_percentage" json:"fee_percentage" binding:"omitempty,gte=0,lt=100000"`
+}
+
+// Normalize puts the email and phone into their canonical form, see
+// utils.NormalizeEmail and utils.NormalizePhone.
+func (r *RegisterRequest) Normalize() error {
+	r.Email = utils.NormalizeEmail(r.Email)
+	phone, err := utils.NormalizePhone(r.Phone)
+	r.Phone = phone
+	return err
+}
+
+func (r *LoginRequest) Normalize() error {
+	r.Email = utils.NormalizeEmail(r.Email)
+	return nil
+}
+
+func (r *TransferRequest) Normalize() error {
+	phone, err := utils.NormalizePhone(r.ReceiverPhone)
+	r.ReceiverPhone = phone
+	return err
+}
+
+func (r *ForgotPasswordRequest) Normalize() error {
+	r.Email = utils.NormalizeEmail(r.Email)
+	return nil
+}
+
+func (r *RequestLoginOTPRequest) Normalize() error {
+	phone, err := utils.NormalizePhone(r.Phone)
+	r.Phone = phone
+	return err
+}
+
+func (r *OTPLoginRequest) Normalize() error {
+	phone, err := utils.NormalizePhone(r.Phone)
+	r.Phone = phone
+	return err
+}
+
+func (r *UpdateProfileRequest) Normalize() error {
+	if r.Email != nil {
+		email := utils.NormalizeEmail(*r.Email)
+		r.Email = &email
+	}
+	if r.Phone != nil {
+		phone, err := utils.NormalizePhone(*r.Phone)
+		if err != nil {
+			return err
+		}
+		r.Phone = &phone
+	}
+	return nil
 }
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type EmailVerification struct {
+	VerificationID int       `json:"verification_id" db:"verification_id"`
+	UserID         int       `json:"user_id" db:"user_id"`
+	TokenHash      string    `json:"-" db:"token_hash"`
+	ExpiresAt      time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	IsUsed         bool      `json:"is_used" db:"is_used"`
+}
+
+type EmailVerificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEmailVerificationRepository(db *pgxpool.Pool) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+// CreateVerification stores a new verification token and invalidates older ones.
+func (r *EmailVerificationRepository) CreateVerification(userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(),
+		`UPDATE email_verifications SET is_used = true WHERE user_id = $1 AND is_used = false`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+		INSERT INTO email_verifications (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)`,
+		userID, tokenHash, expiresAt, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// GetLastSentAt returns when the latest token was issued, or nil if none was.
+func (r *EmailVerificationRepository) GetLastSentAt(userID int) (*time.Time, error) {
+	var lastSentAt *time.Time
+	err := r.db.QueryRow(context.Background(),
+		`SELECT MAX(created_at) FROM email_verifications WHERE user_id = $1`, userID).
+		Scan(&lastSentAt)
+	return lastSentAt, err
+}
+
+// ConsumeVerification marks the token as used and completes the registration.
+// Returns pgx.ErrNoRows when the token is unknown, used or expired.
+func (r *EmailVerificationRepository) ConsumeVerification(tokenHash string) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	var userID int
+	err = tx.QueryRow(context.Background(), `
+		UPDATE email_verifications SET is_used = true
+		WHERE token_hash = $1 AND is_used = false AND expires_at > $2
+		RETURNING user_id`,
+		tokenHash, time.Now()).Scan(&userID)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(context.Background(), `
+		UPDATE users SET registration_status = 'completed', updated_at = $1
+		WHERE user_id = $2 AND registration_status = 'pending' AND is_active = true`,
+		time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return tx.Commit(context.Background())
+}
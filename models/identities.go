@@ -0,0 +1,214 @@
+package models
+
+import (
+	"backend-ewallet/utils"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// identityRow is the part of a user the normalization looks at.
+type identityRow struct {
+	UserID    int        `db:"user_id"`
+	Email     string     `db:"email"`
+	Phone     string     `db:"phone"`
+	Balance   Money      `db:"balance"`
+	IsActive  bool       `db:"is_active"`
+	LastLogin *time.Time `db:"last_login"`
+	CreatedAt time.Time  `db:"created_at"`
+	ClosedAt  *time.Time `db:"closed_at"`
+	// HasTransactions is set when the user sent or received anything.
+	HasTransactions bool `db:"has_transactions"`
+}
+
+// placeholderIdentity matches the emails and phones that closing an account
+// or deduplicating it here put in place of the real ones.
+var placeholderIdentity = regexp.MustCompile(`^(closed|merged)-\d+(@invalid)?$`)
+
+// IdentityReport lists what NormalizeIdentities changed or could not change.
+type IdentityReport struct {
+	Normalized  []string
+	Deactivated []string
+	Conflicts   []string
+	Invalid     []string
+}
+
+type identityChange struct {
+	userID     int
+	column     string
+	value      string
+	deactivate bool
+}
+
+// NormalizeIdentities rewrites every email and phone into its canonical form.
+// Users that collapse onto the same email or phone are deduplicated: the
+// account still in use keeps the identity, the others are deactivated and
+// their email or phone replaced by a placeholder that frees the unique slot.
+// When two accounts in use collide (active, and holding a balance, ever
+// logged in or with transactions) nothing is touched and the group is
+// reported for manual merging, as are unparseable phones of active users.
+// Closed accounts and placeholders are left alone.
+// With dryRun the report is produced without writing anything.
+func NormalizeIdentities(db *pgxpool.Pool, dryRun bool) (*IdentityReport, error) {
+	tx, err := db.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	rows, err := tx.Query(context.Background(), `
+		SELECT u.user_id, u.email, u.phone, u.balance, u.is_active, u.last_login, u.created_at, u.closed_at,
+			EXISTS (
+				SELECT 1 FROM transactions t
+				WHERE t.sender_id = u.user_id OR t.receiver_id = u.user_id
+			) AS has_transactions
+		FROM users u ORDER BY u.user_id
+		FOR UPDATE OF u`)
+	if err != nil {
+		return nil, err
+	}
+	users, err := pgx.CollectRows[identityRow](rows, pgx.RowToStructByName)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &IdentityReport{}
+	emailChanges := planIdentityColumn(users, "email", func(u identityRow) string { return u.Email },
+		func(value string) (string, error) { return utils.NormalizeEmail(value), nil },
+		func(u identityRow) string { return fmt.Sprintf("merged-%d@invalid", u.UserID) }, report)
+	phoneChanges := planIdentityColumn(users, "phone", func(u identityRow) string { return u.Phone },
+		utils.NormalizePhone,
+		func(u identityRow) string { return fmt.Sprintf("merged-%d", u.UserID) }, report)
+	changes := append(emailChanges, phoneChanges...)
+
+	// Placeholders first, so the canonical values are free when the winners take them
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].deactivate && !changes[j].deactivate })
+
+	if dryRun {
+		return report, nil
+	}
+
+	for _, change := range changes {
+		query := fmt.Sprintf(`UPDATE users SET %s = $1, updated_at = $2 WHERE user_id = $3`, change.column)
+		if change.deactivate {
+			query = fmt.Sprintf(`
+				UPDATE users SET %s = $1, is_active = false, token_version = token_version + 1, updated_at = $2
+				WHERE user_id = $3`, change.column)
+		}
+		if _, err := tx.Exec(context.Background(), query, change.value, time.Now(), change.userID); err != nil {
+			return nil, fmt.Errorf("user %d: %w", change.userID, err)
+		}
+	}
+
+	return report, tx.Commit(context.Background())
+}
+
+func planIdentityColumn(
+	users []identityRow,
+	column string,
+	current func(identityRow) string,
+	normalize func(string) (string, error),
+	placeholder func(identityRow) string,
+	report *IdentityReport,
+) []identityChange {
+	groups := make(map[string][]identityRow)
+	var keys []string
+	for _, user := range users {
+		if user.ClosedAt != nil || placeholderIdentity.MatchString(current(user)) {
+			continue
+		}
+		// An inactive account that can be normalized still takes part, its
+		// value holds the unique slot the canonical one may need
+		canonical, err := normalize(current(user))
+		if err != nil {
+			if user.IsActive {
+				report.Invalid = append(report.Invalid, fmt.Sprintf("user %d: %s %q cannot be normalized", user.UserID, column, current(user)))
+			}
+			continue
+		}
+		if _, seen := groups[canonical]; !seen {
+			keys = append(keys, canonical)
+		}
+		groups[canonical] = append(groups[canonical], user)
+	}
+
+	var changes []identityChange
+	for _, canonical := range keys {
+		group := groups[canonical]
+		sort.SliceStable(group, func(i, j int) bool { return keepsIdentity(group[i], group[j], canonical, current) })
+
+		if len(group) > 1 && isInUse(group[1]) {
+			report.Conflicts = append(report.Conflicts, fmt.Sprintf("%s %s is shared by %s, merge them by hand",
+				column, canonical, describeUsers(group)))
+			continue
+		}
+
+		winner := group[0]
+		for _, loser := range group[1:] {
+			changes = append(changes, identityChange{
+				userID:     loser.UserID,
+				column:     column,
+				value:      placeholder(loser),
+				deactivate: true,
+			})
+			report.Deactivated = append(report.Deactivated, fmt.Sprintf("user %d: %s %q duplicates user %d",
+				loser.UserID, column, current(loser), winner.UserID))
+		}
+
+		if current(winner) != canonical {
+			changes = append(changes, identityChange{userID: winner.UserID, column: column, value: canonical})
+			report.Normalized = append(report.Normalized, fmt.Sprintf("user %d: %s %q -> %q",
+				winner.UserID, column, current(winner), canonical))
+		}
+	}
+	return changes
+}
+
+// isInUse is an account that can't simply be switched off: someone has used
+// it, so which of two such accounts keeps the identity is for a human to say.
+func isInUse(user identityRow) bool {
+	return user.IsActive && (user.Balance > 0 || user.LastLogin != nil || user.HasTransactions)
+}
+
+// keepsIdentity orders a duplicate group: accounts in use first, then active
+// ones, the one already in canonical form, the most recently used and finally
+// the oldest.
+func keepsIdentity(a, b identityRow, canonical string, current func(identityRow) string) bool {
+	if isInUse(a) != isInUse(b) {
+		return isInUse(a)
+	}
+	if a.IsActive != b.IsActive {
+		return a.IsActive
+	}
+	if (current(a) == canonical) != (current(b) == canonical) {
+		return current(a) == canonical
+	}
+	if (a.LastLogin == nil) != (b.LastLogin == nil) {
+		return a.LastLogin != nil
+	}
+	if a.LastLogin != nil && !a.LastLogin.Equal(*b.LastLogin) {
+		return a.LastLogin.After(*b.LastLogin)
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+func describeUsers(group []identityRow) string {
+	description := ""
+	for i, user := range group {
+		if i > 0 {
+			description += ", "
+		}
+		lastLogin := "never"
+		if user.LastLogin != nil {
+			lastLogin = user.LastLogin.Format(time.RFC3339)
+		}
+		description += fmt.Sprintf("user %d (active: %t, balance: %s, last login: %s, transactions: %t)",
+			user.UserID, user.IsActive, user.Balance, lastLogin, user.HasTransactions)
+	}
+	return description
+}
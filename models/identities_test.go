@@ -0,0 +1,67 @@
+package models
+
+import (
+	"backend-ewallet/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanIdentityColumnLeavesUsedAccountsToHumans(t *testing.T) {
+	loggedIn := time.Now()
+	tests := []struct {
+		name            string
+		other           identityRow
+		wantConflict    bool
+		wantDeactivated bool
+	}{
+		{"unused duplicate", identityRow{UserID: 2, Email: "A@x.com", IsActive: true}, false, true},
+		{"logged in", identityRow{UserID: 2, Email: "A@x.com", IsActive: true, LastLogin: &loggedIn}, true, false},
+		{"has transactions", identityRow{UserID: 2, Email: "A@x.com", IsActive: true, HasTransactions: true}, true, false},
+		{"holds a balance", identityRow{UserID: 2, Email: "A@x.com", IsActive: true, Balance: 100}, true, false},
+		{"inactive with history", identityRow{UserID: 2, Email: "A@x.com", HasTransactions: true}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := []identityRow{
+				{UserID: 1, Email: "a@x.com", IsActive: true, Balance: 500},
+				tt.other,
+			}
+			report := &IdentityReport{}
+			changes := planIdentityColumn(users, "email", func(u identityRow) string { return u.Email },
+				func(value string) (string, error) { return strings.ToLower(value), nil },
+				func(u identityRow) string { return "merged" }, report)
+
+			if got := len(report.Conflicts) == 1; got != tt.wantConflict {
+				t.Errorf("conflicts = %v, want conflict %v", report.Conflicts, tt.wantConflict)
+			}
+			deactivated := len(changes) == 1 && changes[0].userID == 2 && changes[0].deactivate
+			if deactivated != tt.wantDeactivated {
+				t.Errorf("changes = %+v, want user 2 deactivated %v", changes, tt.wantDeactivated)
+			}
+		})
+	}
+}
+
+func TestPlanIdentityColumnSkipsClosedAndPlaceholders(t *testing.T) {
+	closed := time.Now()
+	users := []identityRow{
+		{UserID: 1, Phone: "0812-0000-0001", IsActive: true},
+		{UserID: 2, Phone: "closed-2", ClosedAt: &closed},
+		{UserID: 3, Phone: "merged-3"},
+		{UserID: 4, Phone: "not a phone"},
+		{UserID: 5, Phone: "also not a phone", IsActive: true},
+	}
+
+	report := &IdentityReport{}
+	changes := planIdentityColumn(users, "phone", func(u identityRow) string { return u.Phone },
+		utils.NormalizePhone, func(u identityRow) string { return "merged" }, report)
+
+	if len(report.Invalid) != 1 || !strings.HasPrefix(report.Invalid[0], "user 5:") {
+		t.Errorf("invalid = %v, want only user 5", report.Invalid)
+	}
+	if len(changes) != 1 || changes[0].userID != 1 || changes[0].value != "+6281200000001" {
+		t.Errorf("changes = %+v, want only user 1 normalized", changes)
+	}
+}
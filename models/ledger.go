@@ -0,0 +1,278 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The ledger is the source of truth for every balance. Money only moves
+// through journal entries whose postings sum to zero; a positive posting
+// raises the account's balance, a negative one lowers it. users.balance is
+// a cached projection of the user's ledger account, written by postEntry in
+// the same database transaction as the postings and otherwise only by the
+// repair in CheckLedger. System accounts have no cached balance, they are
+// summed on demand.
+const (
+	AccountFeeRevenue     = "fee_revenue"
+	AccountTopupClearing  = "topup_clearing"
+	AccountOpeningBalance = "opening_balance"
+)
+
+var ErrUnbalancedEntry = errors.New("journal entry does not balance")
+
+type LedgerAccount struct {
+	AccountID   int       `json:"account_id" db:"account_id"`
+	AccountType string    `json:"account_type" db:"account_type"`
+	UserID      *int      `json:"user_id" db:"user_id"`
+	Code        *string   `json:"code" db:"code"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+type JournalEntry struct {
+	EntryID       int       `json:"entry_id" db:"entry_id"`
+	TransactionID *int      `json:"transaction_id" db:"transaction_id"`
+	Description   string    `json:"description" db:"description"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+type Posting struct {
+	PostingID int   `json:"posting_id" db:"posting_id"`
+	EntryID   int   `json:"entry_id" db:"entry_id"`
+	AccountID int   `json:"account_id" db:"account_id"`
+	Amount    Money `json:"amount" db:"amount"`
+	// Memo is what the account holder's history shows, the entry's
+	// description when empty. It is not stored with the posting.
+	Memo string `json:"-" db:"-"`
+}
+
+// lockedUser is what lockBalances reads about a user.
+type lockedUser struct {
+	balance  Money
+	isActive bool
+}
+
+// userAccountID returns the user's ledger account, opening it on first use.
+func userAccountID(tx pgx.Tx, userID int) (int, error) {
+	_, err := tx.Exec(context.Background(), `
+		INSERT INTO ledger_accounts (account_type, user_id) VALUES ('user', $1)
+		ON CONFLICT (user_id) DO NOTHING`, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var accountID int
+	err = tx.QueryRow(context.Background(),
+		`SELECT account_id FROM ledger_accounts WHERE user_id = $1`, userID).Scan(&accountID)
+	return accountID, err
+}
+
+func systemAccountID(tx pgx.Tx, code string) (int, error) {
+	var accountID int
+	err := tx.QueryRow(context.Background(),
+		`SELECT account_id FROM ledger_accounts WHERE code = $1`, code).Scan(&accountID)
+	if err == pgx.ErrNoRows {
+		return 0, fmt.Errorf("system ledger account %q is missing, run the migrations", code)
+	}
+	return accountID, err
+}
+
+// lockBalances locks the users' rows, in id order so that concurrent
+// entries touching the same users can't deadlock, and returns their cached
+// balances and status. Users that don't exist are missing from the map.
+func lockBalances(tx pgx.Tx, userIDs ...int) (map[int]lockedUser, error) {
+	rows, err := tx.Query(context.Background(), `
+		SELECT user_id, balance, is_active FROM users
+		WHERE user_id = ANY($1)
+		ORDER BY user_id
+		FOR UPDATE`, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[int]lockedUser)
+	var userID int
+	var user lockedUser
+	_, err = pgx.ForEachRow(rows, []any{&userID, &user.balance, &user.isActive}, func() error {
+		users[userID] = user
+		return nil
+	})
+	return users, err
+}
+
+// postEntry books a journal entry and refreshes the cached balance of every
+// user account it touches, returning the new balances by user id. When the
+// entry belongs to a transaction each of those users also gets a
+// transaction_history row. Postings of zero are dropped. The caller should
+// hold the users' row locks, see lockBalances.
+func postEntry(tx pgx.Tx, transactionID *int, description string, postings []Posting) (map[int]Money, error) {
+	var sum Money
+	var nonZero []Posting
+	for _, posting := range postings {
+		sum += posting.Amount
+		if posting.Amount != 0 {
+			nonZero = append(nonZero, posting)
+		}
+	}
+	if sum != 0 {
+		return nil, ErrUnbalancedEntry
+	}
+	sort.Slice(nonZero, func(i, j int) bool { return nonZero[i].AccountID < nonZero[j].AccountID })
+
+	var entryID int
+	err := tx.QueryRow(context.Background(), `
+		INSERT INTO journal_entries (transaction_id, description, created_at)
+		VALUES ($1, $2, $3)
+		RETURNING entry_id`, transactionID, description, time.Now()).Scan(&entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[int]Money)
+	for _, posting := range nonZero {
+		_, err := tx.Exec(context.Background(),
+			`INSERT INTO postings (entry_id, account_id, amount) VALUES ($1, $2, $3)`,
+			entryID, posting.AccountID, posting.Amount)
+		if err != nil {
+			return nil, err
+		}
+
+		var userID int
+		var balance Money
+		err = tx.QueryRow(context.Background(), `
+			UPDATE users u SET balance = u.balance + $1, updated_at = $2
+			FROM ledger_accounts a
+			WHERE a.account_id = $3 AND u.user_id = a.user_id
+			RETURNING u.user_id, u.balance`, posting.Amount, time.Now(), posting.AccountID).
+			Scan(&userID, &balance)
+		if err == pgx.ErrNoRows {
+			// A system account
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		balances[userID] = balance
+
+		if transactionID == nil {
+			continue
+		}
+		summary := posting.Memo
+		if summary == "" {
+			summary = description
+		}
+		_, err = tx.Exec(context.Background(), `
+			INSERT INTO transaction_history (user_id, transaction_id, transaction_summary,
+				balance_before, balance_after, recorded_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			userID, *transactionID, summary, balance-posting.Amount, balance, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return balances, nil
+}
+
+// LedgerReport is the outcome of CheckLedger.
+type LedgerReport struct {
+	Unbalanced     []string
+	Drifted        []string
+	SystemBalances []string
+}
+
+// CheckLedger verifies that every journal entry balances and that each
+// user's cached balance equals the sum of the postings on their account.
+// With fix, drifted balances are rewritten from the ledger. That repairs the
+// projection, it doesn't move money: no journal entry or history row is
+// written, so the next history row's balance_before differs from the last
+// balance_after by the drift that was corrected.
+func CheckLedger(db *pgxpool.Pool, fix bool) (*LedgerReport, error) {
+	tx, err := db.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	report := &LedgerReport{}
+
+	var entryID int
+	var sum Money
+	rows, err := tx.Query(context.Background(), `
+		SELECT entry_id, SUM(amount) FROM postings
+		GROUP BY entry_id HAVING SUM(amount) <> 0
+		ORDER BY entry_id`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = pgx.ForEachRow(rows, []any{&entryID, &sum}, func() error {
+		report.Unbalanced = append(report.Unbalanced, fmt.Sprintf("entry %d is off by %s", entryID, sum))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lockClause := ""
+	if fix {
+		lockClause = "FOR UPDATE OF u"
+	}
+	rows, err = tx.Query(context.Background(), `
+		SELECT u.user_id, u.balance, COALESCE(
+			(SELECT SUM(p.amount) FROM postings p
+			JOIN ledger_accounts a ON a.account_id = p.account_id
+			WHERE a.user_id = u.user_id), 0) AS derived
+		FROM users u
+		ORDER BY u.user_id `+lockClause)
+	if err != nil {
+		return nil, err
+	}
+	var userID int
+	var cached, derived Money
+	drifted := make(map[int]Money)
+	_, err = pgx.ForEachRow(rows, []any{&userID, &cached, &derived}, func() error {
+		if cached != derived {
+			drifted[userID] = derived
+			report.Drifted = append(report.Drifted, fmt.Sprintf("user %d: cached %s, ledger %s", userID, cached, derived))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var code string
+	rows, err = tx.Query(context.Background(), `
+		SELECT a.code, COALESCE(SUM(p.amount), 0)
+		FROM ledger_accounts a LEFT JOIN postings p ON p.account_id = a.account_id
+		WHERE a.account_type = 'system'
+		GROUP BY a.code ORDER BY a.code`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = pgx.ForEachRow(rows, []any{&code, &sum}, func() error {
+		report.SystemBalances = append(report.SystemBalances, fmt.Sprintf("%s: %s", code, sum))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !fix {
+		return report, nil
+	}
+	for userID, balance := range drifted {
+		_, err := tx.Exec(context.Background(),
+			`UPDATE users SET balance = $1, updated_at = $2 WHERE user_id = $3`, balance, time.Now(), userID)
+		if err != nil {
+			return nil, fmt.Errorf("user %d: %w", userID, err)
+		}
+	}
+
+	return report, tx.Commit(context.Background())
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	LoginFailureInvalidCredentials = "invalid_credentials"
+	LoginFailureLocked             = "locked"
+	LoginFailureIPThrottled        = "ip_throttled"
+)
+
+type LoginFailure struct {
+	FailureID   int       `json:"failure_id" db:"failure_id"`
+	Email       string    `json:"email" db:"email"`
+	UserID      *int      `json:"user_id" db:"user_id"`
+	IPAddress   string    `json:"ip_address" db:"ip_address"`
+	UserAgent   string    `json:"user_agent" db:"user_agent"`
+	Reason      string    `json:"reason" db:"reason"`
+	IsCleared   bool      `json:"is_cleared" db:"is_cleared"`
+	AttemptedAt time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+type LoginFailureRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginFailureRepository(db *pgxpool.Pool) *LoginFailureRepository {
+	return &LoginFailureRepository{db: db}
+}
+
+func (r *LoginFailureRepository) RecordFailure(failure *LoginFailure) error {
+	query := `
+		INSERT INTO login_failures (email, user_id, ip_address, user_agent, reason, attempted_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING failure_id`
+
+	return r.db.QueryRow(context.Background(), query,
+		failure.Email,
+		failure.UserID,
+		failure.IPAddress,
+		failure.UserAgent,
+		failure.Reason,
+		failure.AttemptedAt).
+		Scan(&failure.FailureID)
+}
+
+// GetAccountFailures returns the number of uncleared wrong-password attempts
+// for the email since `since` and when the latest one happened. It works the
+// same whether or not the email belongs to an account.
+func (r *LoginFailureRepository) GetAccountFailures(email string, since time.Time) (int, *time.Time, error) {
+	query := `
+		SELECT COUNT(*), MAX(attempted_at)
+		FROM login_failures
+		WHERE lower(email) = lower($1) AND reason = $2 AND is_cleared = false AND attempted_at > $3`
+
+	var count int
+	var lastFailure *time.Time
+	err := r.db.QueryRow(context.Background(), query, email, LoginFailureInvalidCredentials, since).
+		Scan(&count, &lastFailure)
+	return count, lastFailure, err
+}
+
+func (r *LoginFailureRepository) CountIPFailures(ipAddress string, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM login_failures
+		WHERE ip_address = $1 AND reason = $2 AND attempted_at > $3`
+
+	var count int
+	err := r.db.QueryRow(context.Background(), query, ipAddress, LoginFailureInvalidCredentials, since).Scan(&count)
+	return count, err
+}
+
+// ClearFailures lifts the account lockout. Rows are kept for auditing.
+func (r *LoginFailureRepository) ClearFailures(email string) error {
+	query := `UPDATE login_failures SET is_cleared = true WHERE lower(email) = lower($1) AND is_cleared = false`
+	_, err := r.db.Exec(context.Background(), query, email)
+	return err
+}
@@ -0,0 +1,658 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// MemoryStore keeps users, transactions, payment methods, sessions and
+// revoked tokens in memory so the auth middleware and the transaction
+// handlers can be exercised with httptest without a database. It mirrors
+// the constraints the schema enforces: unique email and phone (reported as
+// the same unique violation Postgres raises), non-negative balances, the
+// is_active filters and pgx.ErrNoRows for missing rows. One mutex guards
+// everything, which makes ProcessTransfer and CloseAccount atomic.
+type MemoryStore struct {
+	mu                sync.Mutex
+	users             map[int]*User
+	transactions      map[int]*Transaction
+	paymentMethods    map[int]*PaymentMethod
+	history           []TransactionHistory
+	sessions          map[int]*Session
+	revokedTokens     map[string]time.Time
+	nextUserID        int
+	nextTransactionID int
+	nextMethodID      int
+	nextSessionID     int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:          make(map[int]*User),
+		transactions:   make(map[int]*Transaction),
+		paymentMethods: make(map[int]*PaymentMethod),
+		sessions:       make(map[int]*Session),
+		revokedTokens:  make(map[string]time.Time),
+	}
+}
+
+// AddPaymentMethod stores a payment method and assigns its id and version.
+func (s *MemoryStore) AddPaymentMethod(method *PaymentMethod) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextMethodID++
+	method.MethodID = s.nextMethodID
+	method.VersionID = 1
+	stored := *method
+	s.paymentMethods[method.MethodID] = &stored
+}
+
+func constraintError(code string, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           code,
+		Message:        fmt.Sprintf("constraint %q violated", constraint),
+		ConstraintName: constraint,
+	}
+}
+
+// checkUnique fails when another user already holds email or phone.
+func (s *MemoryStore) checkUnique(userID int, email string, phone string) error {
+	for _, user := range s.users {
+		if user.UserID == userID {
+			continue
+		}
+		if user.Email == email {
+			return constraintError("23505", "users_email_key")
+		}
+		if user.Phone == phone {
+			return constraintError("23505", "users_phone_key")
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateUser(user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUnique(0, user.Email, user.Phone); err != nil {
+		return err
+	}
+	if user.Balance < 0 {
+		return constraintError("23514", "users_balance_check")
+	}
+
+	s.nextUserID++
+	user.UserID = s.nextUserID
+	stored := *user
+	stored.TokenVersion = 0
+	stored.PinFailedAttempts = 0
+	stored.PinLockedUntil = nil
+	stored.LastLogin = nil
+	stored.PhoneVerifiedAt = nil
+	stored.ClosedAt = nil
+	if stored.Role == "" {
+		stored.Role = RoleUser
+	}
+	s.users[stored.UserID] = &stored
+	return nil
+}
+
+// findUser returns a copy of the first user matching, or pgx.ErrNoRows.
+func (s *MemoryStore) findUser(match func(*User) bool) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if match(user) {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (s *MemoryStore) GetUserByEmail(email string) (*User, error) {
+	return s.findUser(func(u *User) bool { return u.Email == email && u.IsActive })
+}
+
+func (s *MemoryStore) GetUserByPhone(phone string) (*User, error) {
+	return s.findUser(func(u *User) bool { return u.Phone == phone && u.IsActive })
+}
+
+func (s *MemoryStore) GetUserByID(userID int) (*User, error) {
+	return s.findUser(func(u *User) bool { return u.UserID == userID && u.IsActive })
+}
+
+func (s *MemoryStore) GetAnyUserByID(userID int) (*User, error) {
+	return s.findUser(func(u *User) bool { return u.UserID == userID })
+}
+
+func (s *MemoryStore) SearchUsers(keyword string, limit int, offset int) ([]User, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keyword = strings.ToLower(keyword)
+	matches := []User{}
+	for _, user := range s.users {
+		if keyword == "" ||
+			strings.Contains(strings.ToLower(user.Email), keyword) ||
+			strings.Contains(strings.ToLower(user.Phone), keyword) ||
+			strings.Contains(strings.ToLower(user.FullName), keyword) {
+			matches = append(matches, *user)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].UserID < matches[j].UserID })
+
+	total := len(matches)
+	if offset > total {
+		offset = total
+	}
+	end := min(offset+limit, total)
+	return matches[offset:end], total, nil
+}
+
+// updateUser applies change to the user if it exists, like an UPDATE that
+// matches no row it is not an error when it doesn't.
+func (s *MemoryStore) updateUser(userID int, change func(*User)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[userID]; ok {
+		change(user)
+	}
+}
+
+func (s *MemoryStore) UpdateLastLogin(userID int) error {
+	now := time.Now()
+	s.updateUser(userID, func(u *User) { u.LastLogin = &now })
+	return nil
+}
+
+func (s *MemoryStore) MarkPhoneVerified(userID int) error {
+	now := time.Now()
+	s.updateUser(userID, func(u *User) {
+		u.PhoneVerifiedAt = &now
+		u.UpdatedAt = now
+	})
+	return nil
+}
+
+func (s *MemoryStore) ReservePinAttempt(userID int, maxAttempts int, lockUntil time.Time) (int, *time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return 0, nil, pgx.ErrNoRows
+	}
+	if user.PinLockedUntil != nil && time.Now().Before(*user.PinLockedUntil) {
+		return 0, user.PinLockedUntil, ErrPinLocked
+	}
+	if user.PinFailedAttempts+1 >= maxAttempts {
+		user.PinLockedUntil = &lockUntil
+		user.PinFailedAttempts = 0
+	} else {
+		user.PinLockedUntil = nil
+		user.PinFailedAttempts++
+	}
+	return user.PinFailedAttempts, user.PinLockedUntil, nil
+}
+
+func (s *MemoryStore) ResetPinFailures(userID int) error {
+	s.updateUser(userID, func(u *User) {
+		u.PinFailedAttempts = 0
+		u.PinLockedUntil = nil
+	})
+	return nil
+}
+
+func (s *MemoryStore) UpdatePin(userID int, pinHash string) error {
+	s.updateUser(userID, func(u *User) {
+		u.PinHash = pinHash
+		u.PinFailedAttempts = 0
+		u.PinLockedUntil = nil
+		u.UpdatedAt = time.Now()
+	})
+	return nil
+}
+
+func (s *MemoryStore) UpdateProfile(user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user.UpdatedAt = time.Now()
+	stored, ok := s.users[user.UserID]
+	if !ok {
+		return nil
+	}
+	if err := s.checkUnique(user.UserID, user.Email, user.Phone); err != nil {
+		return err
+	}
+	stored.FullName = user.FullName
+	stored.Email = user.Email
+	stored.Phone = user.Phone
+	stored.RegistrationStatus = user.RegistrationStatus
+	stored.PhoneVerifiedAt = user.PhoneVerifiedAt
+	stored.UpdatedAt = user.UpdatedAt
+	return nil
+}
+
+func (s *MemoryStore) UpdatePassword(userID int, passwordHash string) error {
+	s.updateUser(userID, func(u *User) {
+		u.PasswordHash = passwordHash
+		u.UpdatedAt = time.Now()
+	})
+	return nil
+}
+
+func (s *MemoryStore) RehashPassword(userID int, oldHash string, newHash string) error {
+	s.updateUser(userID, func(u *User) {
+		if u.PasswordHash == oldHash {
+			u.PasswordHash = newHash
+		}
+	})
+	return nil
+}
+
+func (s *MemoryStore) RehashPin(userID int, oldHash string, newHash string) error {
+	s.updateUser(userID, func(u *User) {
+		if u.PinHash == oldHash {
+			u.PinHash = newHash
+		}
+	})
+	return nil
+}
+
+// CloseAccount applies the same checks and anonymization as
+// UserRepository.CloseAccount to the data the store holds.
+func (s *MemoryStore) CloseAccount(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || !user.IsActive {
+		return pgx.ErrNoRows
+	}
+	if user.Balance != 0 {
+		return ErrAccountHasBalance
+	}
+	for _, tx := range s.transactions {
+		if involves(tx, userID) && (tx.Status == "pending" || tx.Status == "processing") {
+			return ErrAccountHasPendingTransactions
+		}
+	}
+
+	now := time.Now()
+	user.Email = fmt.Sprintf("closed-%d@invalid", userID)
+	user.Phone = fmt.Sprintf("closed-%d", userID)
+	user.FullName = "Closed account"
+	user.PasswordHash = ""
+	user.PinHash = ""
+	user.PhoneVerifiedAt = nil
+	user.PinFailedAttempts = 0
+	user.PinLockedUntil = nil
+	user.IsActive = false
+	user.TokenVersion++
+	user.ClosedAt = &now
+	user.UpdatedAt = now
+	return nil
+}
+
+func involves(tx *Transaction, userID int) bool {
+	return (tx.SenderID != nil && *tx.SenderID == userID) || (tx.ReceiverID != nil && *tx.ReceiverID == userID)
+}
+
+// insertTransaction checks the foreign keys and stores a copy of tx.
+func (s *MemoryStore) insertTransaction(tx *Transaction) error {
+	for _, id := range []*int{tx.SenderID, tx.ReceiverID} {
+		if id != nil {
+			if _, ok := s.users[*id]; !ok {
+				return constraintError("23503", "transactions_user_fkey")
+			}
+		}
+	}
+	if tx.PaymentMethodID != nil {
+		if _, ok := s.paymentMethods[*tx.PaymentMethodID]; !ok {
+			return constraintError("23503", "transactions_method_id_fkey")
+		}
+	}
+
+	s.nextTransactionID++
+	tx.TransactionID = s.nextTransactionID
+	if tx.CreatedAt.IsZero() {
+		tx.CreatedAt = time.Now()
+	}
+	stored := *tx
+	s.transactions[stored.TransactionID] = &stored
+	return nil
+}
+
+func (s *MemoryStore) userTransactions(userID int) []Transaction {
+	transactions := []Transaction{}
+	for _, tx := range s.transactions {
+		if involves(tx, userID) {
+			transactions = append(transactions, *tx)
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		if !transactions[i].CreatedAt.Equal(transactions[j].CreatedAt) {
+			return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+		}
+		return transactions[i].TransactionID < transactions[j].TransactionID
+	})
+	return transactions
+}
+
+func (s *MemoryStore) GetTransactionsByUserID(userID int, limit int) ([]Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transactions := s.userTransactions(userID)
+	slices.Reverse(transactions)
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+	}
+	return transactions, nil
+}
+
+func (s *MemoryStore) GetAllTransactionsByUserID(userID int) ([]Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.userTransactions(userID), nil
+}
+
+// ProcessTransfer moves the money and records the transfer in one step, with
+// the same results as TransactionRepository.ProcessTransfer, including
+// pgx.ErrNoRows for an insufficient balance. Nothing changes when any part
+// fails.
+func (s *MemoryStore) ProcessTransfer(senderID int, receiverID int, transactionType string, amount Money, fee Money, description string, referenceNumber string, status string) (*TransactionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sender, ok := s.users[senderID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	receiver, ok := s.users[receiverID]
+	if !ok || !receiver.IsActive {
+		return nil, fmt.Errorf("receiver %d not found", receiverID)
+	}
+	senderBalance := sender.Balance
+
+	totalAmount := amount + fee
+	if senderBalance < totalAmount {
+		return nil, pgx.ErrNoRows
+	}
+
+	now := time.Now()
+	transaction := &Transaction{
+		SenderID:        &senderID,
+		ReceiverID:      &receiverID,
+		TransactionType: transactionType,
+		Amount:          amount,
+		Fee:             fee,
+		Description:     description,
+		ReferenceNumber: referenceNumber,
+		Status:          status,
+		CreatedAt:       now,
+		CompletedAt:     &now,
+	}
+	if err := s.insertTransaction(transaction); err != nil {
+		return nil, err
+	}
+
+	s.changeBalance(sender, transaction.TransactionID, -totalAmount, transferMemo("Transfer sent", description))
+	s.changeBalance(receiver, transaction.TransactionID, amount, transferMemo("Transfer received", description))
+
+	return &TransactionResponse{
+		TransactionID:   transaction.TransactionID,
+		ReferenceNumber: referenceNumber,
+		TransactionType: transactionType,
+		Amount:          amount,
+		Fee:             fee,
+		Status:          status,
+		NewBalance:      sender.Balance,
+	}, nil
+}
+
+func (s *MemoryStore) ProcessTopup(topup *Transaction) (*TransactionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[*topup.ReceiverID]
+	if !ok || !user.IsActive {
+		return nil, pgx.ErrNoRows
+	}
+
+	now := time.Now()
+	topup.CreatedAt = now
+	if topup.Status == "completed" {
+		topup.CompletedAt = &now
+	}
+	if err := s.insertTransaction(topup); err != nil {
+		return nil, err
+	}
+	s.changeBalance(user, topup.TransactionID, topup.Amount, topup.Description)
+
+	return &TransactionResponse{
+		TransactionID:   topup.TransactionID,
+		ReferenceNumber: topup.ReferenceNumber,
+		TransactionType: topup.TransactionType,
+		Amount:          topup.Amount,
+		Fee:             topup.Fee,
+		Status:          topup.Status,
+		NewBalance:      user.Balance,
+	}, nil
+}
+
+// changeBalance moves the user's balance and records it in the history,
+// the in-memory counterpart of postEntry.
+func (s *MemoryStore) changeBalance(user *User, transactionID int, amount Money, summary string) {
+	if amount == 0 {
+		return
+	}
+	now := time.Now()
+	s.history = append(s.history, TransactionHistory{
+		ID:                 len(s.history) + 1,
+		UserID:             user.UserID,
+		TransactionID:      transactionID,
+		TransactionSummary: summary,
+		BalanceBefore:      user.Balance,
+		BalanceAfter:       user.Balance + amount,
+		RecordedAt:         now,
+	})
+	user.Balance += amount
+	user.UpdatedAt = now
+}
+
+func (s *MemoryStore) GetStatement(userID int, from time.Time, to time.Time) (*Statement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	var previous, next *TransactionHistory
+	var lines []StatementLine
+	for i := range s.history {
+		entry := &s.history[i]
+		if entry.UserID != userID {
+			continue
+		}
+		switch {
+		case entry.RecordedAt.Before(from):
+			previous = entry
+		case entry.RecordedAt.Before(to):
+			tx := s.transactions[entry.TransactionID]
+			lines = append(lines, StatementLine{
+				HistoryID:       entry.ID,
+				TransactionID:   entry.TransactionID,
+				TransactionType: tx.TransactionType,
+				ReferenceNumber: tx.ReferenceNumber,
+				Summary:         entry.TransactionSummary,
+				Counterparty:    s.counterparty(tx, userID),
+				Change:          entry.BalanceAfter - entry.BalanceBefore,
+				BalanceBefore:   entry.BalanceBefore,
+				BalanceAfter:    entry.BalanceAfter,
+				RecordedAt:      entry.RecordedAt,
+			})
+		}
+		if next == nil && !entry.RecordedAt.Before(from) {
+			next = entry
+		}
+	}
+
+	opening := user.Balance
+	if previous != nil {
+		opening = previous.BalanceAfter
+	} else if next != nil {
+		opening = next.BalanceBefore
+	}
+	return newStatement(from, to, opening, lines), nil
+}
+
+// counterparty is the current name of the other user of a transfer.
+func (s *MemoryStore) counterparty(tx *Transaction, userID int) *string {
+	otherID := tx.SenderID
+	if otherID != nil && *otherID == userID {
+		otherID = tx.ReceiverID
+	}
+	if otherID == nil {
+		return nil
+	}
+	other, ok := s.users[*otherID]
+	if !ok {
+		return nil
+	}
+	name := other.FullName
+	return &name
+}
+
+func (s *MemoryStore) GetPaymentMethodByID(methodID int) (*PaymentMethod, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	method, ok := s.paymentMethods[methodID]
+	if !ok || !method.IsActive {
+		return nil, pgx.ErrNoRows
+	}
+	found := *method
+	return &found, nil
+}
+
+func (s *MemoryStore) CreateSession(session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[session.UserID]; !ok {
+		return constraintError("23503", "sessions_user_id_fkey")
+	}
+	s.nextSessionID++
+	session.SessionID = s.nextSessionID
+	stored := *session
+	s.sessions[stored.SessionID] = &stored
+	return nil
+}
+
+func (s *MemoryStore) GetActiveSessionsByUserID(userID int) ([]Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := []Session{}
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			sessions = append(sessions, *session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	return sessions, nil
+}
+
+// activeSession returns the user's session unless it was revoked.
+func (s *MemoryStore) activeSession(sessionID int, userID int) (*Session, bool) {
+	session, ok := s.sessions[sessionID]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return nil, false
+	}
+	return session, true
+}
+
+func (s *MemoryStore) TouchSession(sessionID int, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.activeSession(sessionID, userID)
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	session.LastSeenAt = time.Now()
+	return nil
+}
+
+func (s *MemoryStore) RevokeSession(sessionID int, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.activeSession(sessionID, userID)
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	return nil
+}
+
+func (s *MemoryStore) RevokeAllUserSessions(userID int) error {
+	return s.RevokeOtherSessions(userID, 0)
+}
+
+// RevokeOtherSessions revokes every other session of the user. With
+// keepSessionID 0, as RevokeAllUserSessions calls it, the token version is
+// bumped too, which invalidates the access tokens.
+func (s *MemoryStore) RevokeOtherSessions(userID int, keepSessionID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.SessionID != keepSessionID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+	if user, ok := s.users[userID]; ok && keepSessionID == 0 {
+		user.TokenVersion++
+		user.UpdatedAt = now
+	}
+	return nil
+}
+
+func (s *MemoryStore) RevokeToken(jti string, userID int, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.revokedTokens[jti]; !ok {
+		s.revokedTokens[jti] = expiresAt
+	}
+	return nil
+}
+
+func (s *MemoryStore) IsRevoked(jti string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.revokedTokens[jti]
+	return ok, nil
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestMemoryStoreCreateUserUniqueness(t *testing.T) {
+	store := NewMemoryStore()
+	if err := store.CreateUser(&User{Email: "a@example.com", Phone: "+6281200000001", IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		user  User
+		taken bool
+	}{
+		{"same email", User{Email: "a@example.com", Phone: "+6281200000002"}, true},
+		{"same phone", User{Email: "b@example.com", Phone: "+6281200000001"}, true},
+		{"new email and phone", User{Email: "c@example.com", Phone: "+6281200000003"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.CreateUser(&tt.user)
+			if got := IsUniqueViolation(err); got != tt.taken {
+				t.Errorf("CreateUser() error = %v, unique violation = %v, want %v", err, got, tt.taken)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTransferInsufficientBalance(t *testing.T) {
+	store := NewMemoryStore()
+	sender := &User{Email: "a@example.com", Phone: "+6281200000001", Balance: 1000, IsActive: true}
+	receiver := &User{Email: "b@example.com", Phone: "+6281200000002", IsActive: true}
+	for _, user := range []*User{sender, receiver} {
+		if err := store.CreateUser(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Like the Postgres repository, a balance that can't cover amount plus
+	// fee is pgx.ErrNoRows and nothing is written.
+	_, err := store.ProcessTransfer(sender.UserID, receiver.UserID, "transfer", 1000, 10, "", "REF1", "completed")
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("ProcessTransfer() error = %v, want pgx.ErrNoRows", err)
+	}
+	if got, _ := store.GetUserByID(sender.UserID); got.Balance != 1000 {
+		t.Errorf("sender balance = %s, want 10.00", got.Balance)
+	}
+	if transactions, _ := store.GetAllTransactionsByUserID(sender.UserID); len(transactions) != 0 {
+		t.Errorf("got %d transactions, want none", len(transactions))
+	}
+
+	res, err := store.ProcessTransfer(sender.UserID, receiver.UserID, "transfer", 990, 10, "", "REF2", "completed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NewBalance != 0 {
+		t.Errorf("new balance = %s, want 0.00", res.NewBalance)
+	}
+}
+
+func TestMemoryStoreStatementHidesClosedAccountNames(t *testing.T) {
+	store := NewMemoryStore()
+	sender := &User{Email: "a@example.com", Phone: "+6281200000001", FullName: "Alice Example", Balance: 1010, IsActive: true}
+	receiver := &User{Email: "b@example.com", Phone: "+6281200000002", FullName: "Bob Example", IsActive: true}
+	for _, user := range []*User{sender, receiver} {
+		if err := store.CreateUser(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	from := time.Now().Add(-time.Minute)
+	if _, err := store.ProcessTransfer(sender.UserID, receiver.UserID, "transfer", 1000, 10, "rent", "REF1", "completed"); err != nil {
+		t.Fatal(err)
+	}
+
+	statement, err := store.GetStatement(receiver.UserID, from, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statement.Lines) != 1 || statement.Lines[0].Counterparty == nil || *statement.Lines[0].Counterparty != "Alice Example" {
+		t.Fatalf("lines before closing = %+v, want one from Alice Example", statement.Lines)
+	}
+
+	if err := store.CloseAccount(sender.UserID); err != nil {
+		t.Fatal(err)
+	}
+	statement, err = store.GetStatement(receiver.UserID, from, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := statement.Lines[0]
+	if line.Counterparty == nil || *line.Counterparty != "Closed account" {
+		t.Errorf("counterparty after closing = %v, want Closed account", line.Counterparty)
+	}
+	if strings.Contains(line.Summary, "Alice") || line.Summary != "Transfer received: rent" {
+		t.Errorf("summary = %q, want \"Transfer received: rent\"", line.Summary)
+	}
+}
@@ -0,0 +1,219 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const MFAMaxAttempts = 5
+
+type UserTOTP struct {
+	UserID          int        `json:"user_id" db:"user_id"`
+	SecretEncrypted string     `json:"-" db:"secret_encrypted"`
+	LastUsedStep    int64      `json:"-" db:"last_used_step"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	ConfirmedAt     *time.Time `json:"confirmed_at" db:"confirmed_at"`
+}
+
+type MFAChallenge struct {
+	ChallengeID int        `json:"challenge_id" db:"challenge_id"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	DeviceLabel string     `json:"device_label" db:"device_label"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ConsumedAt  *time.Time `json:"consumed_at" db:"consumed_at"`
+}
+
+type MFARepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMFARepository(db *pgxpool.Pool) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+func (r *MFARepository) GetTOTP(userID int) (*UserTOTP, error) {
+	query := `
+		SELECT user_id, secret_encrypted, last_used_step, created_at, confirmed_at
+		FROM user_totp WHERE user_id = $1`
+
+	row, err := r.db.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	totp, err := pgx.CollectOneRow[UserTOTP](row, pgx.RowToStructByName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &totp, nil
+}
+
+// SaveTOTPSecret stores a pending (unconfirmed) secret, replacing any earlier
+// pending one. Confirmed enrollments are left untouched.
+func (r *MFARepository) SaveTOTPSecret(userID int, secretEncrypted string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret_encrypted, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = 0, created_at = EXCLUDED.created_at
+		WHERE user_totp.confirmed_at IS NULL`
+
+	_, err := r.db.Exec(context.Background(), query, userID, secretEncrypted, time.Now())
+	return err
+}
+
+// ConfirmTOTP enables the enrollment and replaces the user's recovery codes.
+func (r *MFARepository) ConfirmTOTP(userID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	tag, err := tx.Exec(context.Background(), `
+		UPDATE user_totp SET confirmed_at = $1, last_used_step = $2
+		WHERE user_id = $3 AND confirmed_at IS NULL`,
+		time.Now(), step, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	_, err = tx.Exec(context.Background(), `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.Exec(context.Background(), `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at)
+			VALUES ($1, $2, $3)`,
+			userID, hash, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// UseTOTPStep records step as used. Returns pgx.ErrNoRows when the step is not
+// newer than the last one, i.e. the code is being replayed.
+func (r *MFARepository) UseTOTPStep(userID int, step int64) error {
+	query := `UPDATE user_totp SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`
+	tag, err := r.db.Exec(context.Background(), query, step, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *MFARepository) DeleteTOTP(userID int) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), `DELETE FROM user_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// UseRecoveryCode burns a recovery code. Returns pgx.ErrNoRows when it is
+// unknown or already used.
+func (r *MFARepository) UseRecoveryCode(userID int, codeHash string) error {
+	query := `
+		UPDATE mfa_recovery_codes SET used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`
+
+	tag, err := r.db.Exec(context.Background(), query, time.Now(), userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *MFARepository) CreateChallenge(challenge *MFAChallenge) error {
+	query := `
+		INSERT INTO mfa_challenges (user_id, token_hash, device_label, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING challenge_id`
+
+	return r.db.QueryRow(context.Background(), query,
+		challenge.UserID,
+		challenge.TokenHash,
+		challenge.DeviceLabel,
+		challenge.ExpiresAt,
+		challenge.CreatedAt).
+		Scan(&challenge.ChallengeID)
+}
+
+// GetActiveChallenge returns an unexpired, unconsumed challenge or pgx.ErrNoRows.
+func (r *MFARepository) GetActiveChallenge(tokenHash string) (*MFAChallenge, error) {
+	query := `
+		SELECT challenge_id, user_id, token_hash, device_label, attempts,
+			expires_at, created_at, consumed_at
+		FROM mfa_challenges
+		WHERE token_hash = $1 AND consumed_at IS NULL AND expires_at > $2`
+
+	row, err := r.db.Query(context.Background(), query, tokenHash, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := pgx.CollectOneRow[MFAChallenge](row, pgx.RowToStructByName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+// ReserveChallengeAttempt spends one of the challenge's attempts before the
+// code is checked, so parallel guesses cannot exceed maxAttempts. Returns
+// pgx.ErrNoRows once the attempts are used up.
+func (r *MFARepository) ReserveChallengeAttempt(challengeID int, maxAttempts int) (int, error) {
+	query := `
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE challenge_id = $1 AND attempts < $2
+		RETURNING attempts`
+
+	var attempts int
+	err := r.db.QueryRow(context.Background(), query, challengeID, maxAttempts).Scan(&attempts)
+	return attempts, err
+}
+
+// ConsumeChallenge returns pgx.ErrNoRows if the challenge was already redeemed.
+func (r *MFARepository) ConsumeChallenge(challengeID int) error {
+	query := `UPDATE mfa_challenges SET consumed_at = $1 WHERE challenge_id = $2 AND consumed_at IS NULL`
+	tag, err := r.db.Exec(context.Background(), query, time.Now(), challengeID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Money is an amount in minor units (1/100 of a rupiah), matching the
+// DECIMAL(15, 2) money columns exactly. It travels through JSON as a string
+// such as "10000.50" and through pgx as a numeric, so no amount ever passes
+// through a float. Plain integer arithmetic and comparisons apply.
+type Money int64
+
+// Percentage is a rate in ten-thousandths of a percent, matching the
+// DECIMAL(5, 4) fee_percentage column: 2.5% is Percentage(25000).
+type Percentage int64
+
+const (
+	moneyScale      = 2
+	percentageScale = 4
+
+	// MaxMoney is the largest amount a DECIMAL(15, 2) column holds.
+	MaxMoney Money = 999_999_999_999_999
+)
+
+var (
+	ErrInvalidAmount    = errors.New("amount must be a decimal number such as 10000 or 10000.50")
+	ErrAmountTooPrecise = errors.New("amount must not have more than two decimal places")
+	ErrAmountTooLarge   = errors.New("amount is too large")
+)
+
+// RoundingMode decides what happens to the part of a result below one
+// minor unit.
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds to the nearest unit, halves away from zero.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds to the nearest unit, halves to the even one.
+	RoundHalfEven
+	// RoundDown truncates towards zero.
+	RoundDown
+	// RoundUp rounds away from zero.
+	RoundUp
+)
+
+// ParseMoney reads a decimal amount such as "10000", "10000.5" or
+// "-3.25". More than two decimal places is an error, never rounded.
+func ParseMoney(s string) (Money, error) {
+	units, err := parseFixed(s, moneyScale)
+	if err != nil {
+		return 0, err
+	}
+	if units > int64(MaxMoney) || units < -int64(MaxMoney) {
+		return 0, ErrAmountTooLarge
+	}
+	return Money(units), nil
+}
+
+// MustParseMoney is ParseMoney for constants, it panics on invalid input.
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) String() string {
+	return formatFixed(int64(m), moneyScale)
+}
+
+// Percent returns p percent of m, rounded to a whole minor unit with mode.
+func (m Money) Percent(p Percentage, mode RoundingMode) Money {
+	numerator := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(p)))
+	denominator := big.NewInt(100 * pow10(percentageScale))
+	return Money(divRound(numerator, denominator, mode))
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + m.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts a string or a bare number, both read digit by digit.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseMoney(unquoteJSONNumber(data))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// UnmarshalParam lets gin bind form and query values.
+func (m *Money) UnmarshalParam(param string) error {
+	parsed, err := ParseMoney(param)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m *Money) ScanNumeric(n pgtype.Numeric) error {
+	units, err := numericToFixed(n, moneyScale)
+	if err != nil {
+		return err
+	}
+	*m = Money(units)
+	return nil
+}
+
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(int64(m)), Exp: -moneyScale, Valid: true}, nil
+}
+
+// ParsePercentage reads a rate such as "2.5" with up to four decimals.
+func ParsePercentage(s string) (Percentage, error) {
+	units, err := parseFixed(s, percentageScale)
+	if err != nil {
+		return 0, fmt.Errorf("percentage must be a decimal number with at most four decimal places")
+	}
+	return Percentage(units), nil
+}
+
+// MustParsePercentage is ParsePercentage for constants, it panics on invalid input.
+func MustParsePercentage(s string) Percentage {
+	p, err := ParsePercentage(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p Percentage) String() string {
+	return formatFixed(int64(p), percentageScale)
+}
+
+func (p Percentage) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + p.String() + `"`), nil
+}
+
+func (p *Percentage) UnmarshalJSON(data []byte) error {
+	parsed, err := ParsePercentage(unquoteJSONNumber(data))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+func (p *Percentage) UnmarshalParam(param string) error {
+	parsed, err := ParsePercentage(param)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+func (p *Percentage) ScanNumeric(n pgtype.Numeric) error {
+	units, err := numericToFixed(n, percentageScale)
+	if err != nil {
+		return err
+	}
+	*p = Percentage(units)
+	return nil
+}
+
+func (p Percentage) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(int64(p)), Exp: -percentageScale, Valid: true}, nil
+}
+
+func unquoteJSONNumber(data []byte) string {
+	s := string(data)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseFixed reads a plain decimal into an integer count of 10^-scale units.
+func parseFixed(s string, scale int) (int64, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	if negative || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+
+	whole, fraction, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return 0, ErrInvalidAmount
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > scale {
+		if scale == moneyScale {
+			return 0, ErrAmountTooPrecise
+		}
+		return 0, ErrInvalidAmount
+	}
+	if len(whole) > 18-scale {
+		return 0, ErrAmountTooLarge
+	}
+
+	units, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", scale-len(fraction)), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	if negative {
+		units = -units
+	}
+	return units, nil
+}
+
+func formatFixed(units int64, scale int) string {
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	digits := fmt.Sprintf("%0*d", scale+1, units)
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// numericToFixed converts a database numeric into 10^-scale units, refusing
+// values that would need rounding.
+func numericToFixed(n pgtype.Numeric, scale int) (int64, error) {
+	if !n.Valid {
+		return 0, errors.New("cannot scan NULL into a money value")
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return 0, errors.New("cannot scan a non-finite numeric into a money value")
+	}
+
+	value := new(big.Int).Set(n.Int)
+	shift := int(n.Exp) + scale
+	if shift >= 0 {
+		value.Mul(value, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(shift)), nil))
+	} else {
+		remainder := new(big.Int)
+		value.QuoRem(value, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-shift)), nil), remainder)
+		if remainder.Sign() != 0 {
+			return 0, fmt.Errorf("numeric has more than %d decimal places", scale)
+		}
+	}
+	if !value.IsInt64() {
+		return 0, ErrAmountTooLarge
+	}
+	return value.Int64(), nil
+}
+
+// divRound divides and rounds the quotient to an integer with mode.
+func divRound(numerator, denominator *big.Int, mode RoundingMode) int64 {
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient.Int64()
+	}
+
+	direction := int64(numerator.Sign() * denominator.Sign())
+	twiceRemainder := new(big.Int).Abs(remainder)
+	twiceRemainder.Lsh(twiceRemainder, 1)
+	half := twiceRemainder.Cmp(new(big.Int).Abs(denominator))
+
+	roundAway := false
+	switch mode {
+	case RoundHalfUp:
+		roundAway = half >= 0
+	case RoundHalfEven:
+		roundAway = half > 0 || (half == 0 && quotient.Bit(0) == 1)
+	case RoundUp:
+		roundAway = true
+	case RoundDown:
+		roundAway = false
+	}
+	if roundAway {
+		return quotient.Int64() + direction
+	}
+	return quotient.Int64()
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
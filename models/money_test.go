@@ -0,0 +1,259 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr error
+	}{
+		{"10000", 1000000, nil},
+		{"10000.5", 1000050, nil},
+		{"10000.50", 1000050, nil},
+		{"10000.500", 1000050, nil},
+		{"10000.000", 1000000, nil},
+		{" 0.01 ", 1, nil},
+		{"+3.25", 325, nil},
+		{"-3.25", -325, nil},
+		{"0", 0, nil},
+		{"10000.505", 0, ErrAmountTooPrecise},
+		{"0.001", 0, ErrAmountTooPrecise},
+		{"9999999999999.99", MaxMoney, nil},
+		{"-9999999999999.99", -MaxMoney, nil},
+		{"10000000000000", 0, ErrAmountTooLarge},
+		{"-10000000000000.00", 0, ErrAmountTooLarge},
+		{"99999999999999999999", 0, ErrAmountTooLarge},
+		{"", 0, ErrInvalidAmount},
+		{"-", 0, ErrInvalidAmount},
+		{".5", 0, ErrInvalidAmount},
+		{"5.", 0, ErrInvalidAmount},
+		{"1e3", 0, ErrInvalidAmount},
+		{"1,000", 0, ErrInvalidAmount},
+		{"--1", 0, ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseMoney(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{1000050, "10000.50"},
+		{MaxMoney, "9999999999999.99"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestDivRound(t *testing.T) {
+	modes := []struct {
+		name string
+		mode RoundingMode
+	}{
+		{"half up", RoundHalfUp},
+		{"half even", RoundHalfEven},
+		{"down", RoundDown},
+		{"up", RoundUp},
+	}
+	tests := []struct {
+		numerator, denominator int64
+		// want holds the result for each mode, in the order of modes
+		want [4]int64
+	}{
+		{6, 3, [4]int64{2, 2, 2, 2}},
+		{-6, 3, [4]int64{-2, -2, -2, -2}},
+		{0, 7, [4]int64{0, 0, 0, 0}},
+		// exact halves
+		{5, 2, [4]int64{3, 2, 2, 3}},
+		{7, 2, [4]int64{4, 4, 3, 4}},
+		{-5, 2, [4]int64{-3, -2, -2, -3}},
+		{-7, 2, [4]int64{-4, -4, -3, -4}},
+		{5, -2, [4]int64{-3, -2, -2, -3}},
+		{-5, -2, [4]int64{3, 2, 2, 3}},
+		// below and above the half
+		{7, 3, [4]int64{2, 2, 2, 3}},
+		{8, 3, [4]int64{3, 3, 2, 3}},
+		{-7, 3, [4]int64{-2, -2, -2, -3}},
+		{-8, 3, [4]int64{-3, -3, -2, -3}},
+		{1, 3, [4]int64{0, 0, 0, 1}},
+		{-1, 3, [4]int64{0, 0, 0, -1}},
+	}
+
+	for _, tt := range tests {
+		for i, m := range modes {
+			got := divRound(big.NewInt(tt.numerator), big.NewInt(tt.denominator), m.mode)
+			if got != tt.want[i] {
+				t.Errorf("divRound(%d, %d, %s) = %d, want %d", tt.numerator, tt.denominator, m.name, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestMoneyPercent(t *testing.T) {
+	onePercent := MustParsePercentage("1")
+	tests := []struct {
+		amount Money
+		rate   Percentage
+		mode   RoundingMode
+		want   Money
+	}{
+		// the 1% transfer fee, half a unit rounds up
+		{MustParseMoney("0.50"), onePercent, RoundHalfUp, 1},
+		{MustParseMoney("0.49"), onePercent, RoundHalfUp, 0},
+		{MustParseMoney("100.50"), onePercent, RoundHalfUp, 101},
+		{MustParseMoney("10000"), onePercent, RoundHalfUp, 10000},
+		{MustParseMoney("2.50"), onePercent, RoundHalfEven, 2},
+		{MustParseMoney("3.50"), onePercent, RoundHalfEven, 4},
+		{MustParseMoney("0.99"), onePercent, RoundDown, 0},
+		{MustParseMoney("0.01"), onePercent, RoundUp, 1},
+		{MustParseMoney("100"), MustParsePercentage("2.5"), RoundHalfUp, 250},
+		{MustParseMoney("0.10"), MustParsePercentage("0.0001"), RoundHalfUp, 0},
+		{MaxMoney, MustParsePercentage("100"), RoundHalfUp, MaxMoney},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.Percent(tt.rate, tt.mode); got != tt.want {
+			t.Errorf("%s.Percent(%s, %d) = %d, want %d", tt.amount, tt.rate, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestParsePercentage(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Percentage
+		wantErr bool
+	}{
+		{"1", 10000, false},
+		{"2.5", 25000, false},
+		{"0.0001", 1, false},
+		{"2.50000", 25000, false},
+		{"0.00001", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePercentage(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePercentage(%q) = %d, %v, want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNumericToFixed(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      pgtype.Numeric
+		want    int64
+		wantErr bool
+	}{
+		{"same scale", pgtype.Numeric{Int: big.NewInt(1000050), Exp: -2, Valid: true}, 1000050, false},
+		{"fewer decimals", pgtype.Numeric{Int: big.NewInt(5), Exp: 0, Valid: true}, 500, false},
+		{"positive exponent", pgtype.Numeric{Int: big.NewInt(5), Exp: 3, Valid: true}, 500000, false},
+		{"trailing zeros", pgtype.Numeric{Int: big.NewInt(12340), Exp: -3, Valid: true}, 1234, false},
+		{"negative", pgtype.Numeric{Int: big.NewInt(-325), Exp: -2, Valid: true}, -325, false},
+		{"needs rounding", pgtype.Numeric{Int: big.NewInt(12345), Exp: -3, Valid: true}, 0, true},
+		{"too large", pgtype.Numeric{Int: big.NewInt(1), Exp: 30, Valid: true}, 0, true},
+		{"null", pgtype.Numeric{}, 0, true},
+		{"nan", pgtype.Numeric{NaN: true, Valid: true}, 0, true},
+		{"infinity", pgtype.Numeric{InfinityModifier: pgtype.Infinity, Valid: true}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := numericToFixed(tt.in, moneyScale)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("numericToFixed() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("numericToFixed() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyUnmarshal(t *testing.T) {
+	tests := []struct {
+		json    string
+		want    Money
+		wantErr error
+	}{
+		{`"10000.50"`, 1000050, nil},
+		{`10000.5`, 1000050, nil},
+		{`"10000.500"`, 1000050, nil},
+		{`10000`, 1000000, nil},
+		{`"10000.505"`, 0, ErrAmountTooPrecise},
+		{`10000.505`, 0, ErrAmountTooPrecise},
+		{`"10000000000000"`, 0, ErrAmountTooLarge},
+		{`1e4`, 0, ErrInvalidAmount},
+		{`"abc"`, 0, ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		var req TransferRequest
+		err := json.Unmarshal([]byte(`{"amount": `+tt.json+`}`), &req)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("unmarshal %s error = %v, want %v", tt.json, err, tt.wantErr)
+			continue
+		}
+		if req.Amount != tt.want {
+			t.Errorf("unmarshal %s = %d, want %d", tt.json, req.Amount, tt.want)
+		}
+	}
+
+	out, err := json.Marshal(TransferRequest{Amount: 1000050})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back TransferRequest
+	if err := json.Unmarshal(out, &back); err != nil || back.Amount != 1000050 {
+		t.Errorf("round trip through %s = %d, %v", out, back.Amount, err)
+	}
+}
+
+func TestMoneyUnmarshalParam(t *testing.T) {
+	tests := []struct {
+		param   string
+		want    Money
+		wantErr error
+	}{
+		{"10000.50", 1000050, nil},
+		{"10000.500", 1000050, nil},
+		{"10000.505", 0, ErrAmountTooPrecise},
+		{"", 0, ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		var m Money
+		err := m.UnmarshalParam(tt.param)
+		if !errors.Is(err, tt.wantErr) || m != tt.want {
+			t.Errorf("UnmarshalParam(%q) = %d, %v, want %d, %v", tt.param, m, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"backend-ewallet/utils"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	OTPPurposePhoneVerification = "phone_verification"
+	OTPPurposeLogin             = "login"
+	OTPPurposePinReset          = "pin_reset"
+
+	OTPMaxAttempts = 5
+)
+
+var (
+	ErrOTPInvalid         = errors.New("invalid otp")
+	ErrOTPTooManyAttempts = errors.New("too many otp attempts")
+)
+
+type OTPCode struct {
+	OTPID      int        `json:"otp_id" db:"otp_id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	Purpose    string     `json:"purpose" db:"purpose"`
+	CodeHash   string     `json:"-" db:"code_hash"`
+	Attempts   int        `json:"attempts" db:"attempts"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ConsumedAt *time.Time `json:"consumed_at" db:"consumed_at"`
+}
+
+type OTPRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOTPRepository(db *pgxpool.Pool) *OTPRepository {
+	return &OTPRepository{db: db}
+}
+
+// CreateOTP stores a new code and invalidates the previous ones for the same purpose.
+func (r *OTPRepository) CreateOTP(userID int, purpose string, codeHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), `
+		UPDATE otp_codes SET consumed_at = $1
+		WHERE user_id = $2 AND purpose = $3 AND consumed_at IS NULL`,
+		time.Now(), userID, purpose)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+		INSERT INTO otp_codes (user_id, purpose, code_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		userID, purpose, codeHash, expiresAt, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// GetSendStats returns when the last code was sent and how many were sent since `since`.
+func (r *OTPRepository) GetSendStats(userID int, purpose string, since time.Time) (*time.Time, int, error) {
+	var lastSentAt *time.Time
+	var count int
+	err := r.db.QueryRow(context.Background(), `
+		SELECT MAX(created_at), COUNT(*) FILTER (WHERE created_at > $3)
+		FROM otp_codes WHERE user_id = $1 AND purpose = $2`,
+		userID, purpose, since).Scan(&lastSentAt, &count)
+	return lastSentAt, count, err
+}
+
+// VerifyOTP checks code against the active code for the purpose and consumes it
+// on success. Returns pgx.ErrNoRows when there is no active code, ErrOTPInvalid
+// on a wrong code and ErrOTPTooManyAttempts once the attempts are exhausted.
+func (r *OTPRepository) VerifyOTP(userID int, purpose string, code string) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	row, err := tx.Query(context.Background(), `
+		SELECT otp_id, user_id, purpose, code_hash, attempts, expires_at, created_at, consumed_at
+		FROM otp_codes
+		WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL AND expires_at > $3
+		ORDER BY created_at DESC
+		LIMIT 1
+		FOR UPDATE`,
+		userID, purpose, time.Now())
+	if err != nil {
+		return err
+	}
+	otp, err := pgx.CollectOneRow[OTPCode](row, pgx.RowToStructByName)
+	if err != nil {
+		return err
+	}
+
+	if otp.Attempts >= OTPMaxAttempts {
+		return ErrOTPTooManyAttempts
+	}
+
+	if !utils.CheckPasswordHash(code, otp.CodeHash) {
+		_, err = tx.Exec(context.Background(),
+			`UPDATE otp_codes SET attempts = attempts + 1 WHERE otp_id = $1`, otp.OTPID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(context.Background()); err != nil {
+			return err
+		}
+		if otp.Attempts+1 >= OTPMaxAttempts {
+			return ErrOTPTooManyAttempts
+		}
+		return ErrOTPInvalid
+	}
+
+	_, err = tx.Exec(context.Background(),
+		`UPDATE otp_codes SET consumed_at = $1 WHERE otp_id = $2`, time.Now(), otp.OTPID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
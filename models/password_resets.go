@@ -0,0 +1,109 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PasswordReset struct {
+	ResetID   int       `json:"reset_id" db:"reset_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	TokenHash string    `json:"-" db:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	IsUsed    bool      `json:"is_used" db:"is_used"`
+}
+
+type PasswordResetRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordResetRepository(db *pgxpool.Pool) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// CreateReset stores a new reset token and invalidates any older unused ones.
+func (r *PasswordResetRepository) CreateReset(userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(),
+		`UPDATE password_resets SET is_used = true WHERE user_id = $1 AND is_used = false`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+		INSERT INTO password_resets (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)`,
+		userID, tokenHash, expiresAt, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// GetResetUserID returns the owner of a usable reset token, or pgx.ErrNoRows.
+func (r *PasswordResetRepository) GetResetUserID(tokenHash string) (int, error) {
+	var userID int
+	err := r.db.QueryRow(context.Background(), `
+		SELECT user_id FROM password_resets
+		WHERE token_hash = $1 AND is_used = false AND expires_at > $2`,
+		tokenHash, time.Now()).Scan(&userID)
+	return userID, err
+}
+
+// ConsumeReset marks the token as used, sets the new password and bumps the
+// user's token version and revokes sessions and refresh tokens so previously
+// issued credentials stop working.
+// Returns pgx.ErrNoRows when the token is unknown, used or expired.
+func (r *PasswordResetRepository) ConsumeReset(tokenHash string, passwordHash string) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	var userID int
+	err = tx.QueryRow(context.Background(), `
+		UPDATE password_resets SET is_used = true
+		WHERE token_hash = $1 AND is_used = false AND expires_at > $2
+		RETURNING user_id`,
+		tokenHash, time.Now()).Scan(&userID)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(context.Background(), `
+		UPDATE users SET password_hash = $1, updated_at = $2
+		WHERE user_id = $3 AND is_active = true`,
+		passwordHash, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := revokeUserSessions(tx, userID); err != nil {
+		return err
+	}
+
+	// A successful reset is the way out of a login lockout
+	_, err = tx.Exec(context.Background(), `
+		UPDATE login_failures SET is_cleared = true
+		WHERE lower(email) = (SELECT lower(email) FROM users WHERE user_id = $1) AND is_cleared = false`,
+		userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
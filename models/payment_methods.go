@@ -0,0 +1,192 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PaymentMethod struct {
+	MethodID      int        `json:"method_id" db:"method_id"`
+	VersionID     int        `json:"version_id" db:"version_id"`
+	MethodName    string     `json:"method_name" db:"method_name"`
+	MethodType    string     `json:"method_type" db:"method_type"`
+	IsActive      bool       `json:"is_active" db:"is_active"`
+	MinAmount     Money      `json:"min_amount" db:"min_amount"`
+	MaxAmount     Money      `json:"max_amount" db:"max_amount"`
+	FeePercentage Percentage `json:"fee_percentage" db:"fee_percentage"`
+}
+
+type PaymentMethodVersion struct {
+	VersionID     int        `json:"version_id" db:"version_id"`
+	MethodID      int        `json:"method_id" db:"method_id"`
+	MethodName    string     `json:"method_name" db:"method_name"`
+	MethodType    string     `json:"method_type" db:"method_type"`
+	IsActive      bool       `json:"is_active" db:"is_active"`
+	MinAmount     Money      `json:"min_amount" db:"min_amount"`
+	MaxAmount     Money      `json:"max_amount" db:"max_amount"`
+	FeePercentage Percentage `json:"fee_percentage" db:"fee_percentage"`
+	CreatedBy     *int       `json:"created_by" db:"created_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// paymentMethodColumns selects a payment method together with its current
+// version, the query must alias payment_methods as pm.
+const paymentMethodColumns = `pm.method_id,
+	(SELECT MAX(v.version_id) FROM payment_method_versions v WHERE v.method_id = pm.method_id) AS version_id,
+	pm.method_name, pm.method_type, pm.is_active, pm.min_amount, pm.max_amount, pm.fee_percentage`
+
+type PaymentMethodRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPaymentMethodRepository(db *pgxpool.Pool) *PaymentMethodRepository {
+	return &PaymentMethodRepository{db: db}
+}
+
+func (r *PaymentMethodRepository) GetPaymentMethods(activeOnly bool) ([]PaymentMethod, error) {
+	query := `
+		SELECT ` + paymentMethodColumns + `
+		FROM payment_methods pm
+		WHERE $1 = false OR pm.is_active = true
+		ORDER BY pm.method_type, pm.method_id`
+
+	rows, err := r.db.Query(context.Background(), query, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows[PaymentMethod](rows, pgx.RowToStructByName)
+}
+
+// GetAnyPaymentMethodByID loads a method regardless of is_active, for the back-office.
+func (r *PaymentMethodRepository) GetAnyPaymentMethodByID(methodID int) (*PaymentMethod, error) {
+	query := `
+		SELECT ` + paymentMethodColumns + `
+		FROM payment_methods pm WHERE pm.method_id = $1`
+
+	row, err := r.db.Query(context.Background(), query, methodID)
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := pgx.CollectOneRow[PaymentMethod](row, pgx.RowToStructByName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &method, nil
+}
+
+// CreatePaymentMethod inserts the method with its first version. audit is
+// called once both are written, so the entry can describe them, and is
+// stored in the same transaction.
+func (r *PaymentMethodRepository) CreatePaymentMethod(method *PaymentMethod, actorID int, audit func() *AuditLog) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	query := `
+		INSERT INTO payment_methods (method_name, method_type, is_active, min_amount, max_amount, fee_percentage)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING method_id`
+
+	err = tx.QueryRow(context.Background(), query,
+		method.MethodName,
+		method.MethodType,
+		method.IsActive,
+		method.MinAmount,
+		method.MaxAmount,
+		method.FeePercentage).
+		Scan(&method.MethodID)
+	if err != nil {
+		return err
+	}
+
+	method.VersionID, err = recordPaymentMethodVersion(tx, method.MethodID, actorID)
+	if err != nil {
+		return err
+	}
+	if err := insertAuditLog(tx, audit()); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// UpdatePaymentMethod saves the method and appends a new version, earlier
+// versions stay untouched so past top-ups keep pointing at the fee schedule
+// they were charged under. audit works as in CreatePaymentMethod.
+func (r *PaymentMethodRepository) UpdatePaymentMethod(method *PaymentMethod, actorID int, audit func() *AuditLog) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	query := `
+		UPDATE payment_methods
+		SET method_name = $1, method_type = $2, is_active = $3,
+			min_amount = $4, max_amount = $5, fee_percentage = $6
+		WHERE method_id = $7`
+
+	result, err := tx.Exec(context.Background(), query,
+		method.MethodName,
+		method.MethodType,
+		method.IsActive,
+		method.MinAmount,
+		method.MaxAmount,
+		method.FeePercentage,
+		method.MethodID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	method.VersionID, err = recordPaymentMethodVersion(tx, method.MethodID, actorID)
+	if err != nil {
+		return err
+	}
+	if err := insertAuditLog(tx, audit()); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+func (r *PaymentMethodRepository) GetPaymentMethodVersions(methodID int) ([]PaymentMethodVersion, error) {
+	query := `
+		SELECT version_id, method_id, method_name, method_type, is_active,
+			min_amount, max_amount, fee_percentage, created_by, created_at
+		FROM payment_method_versions
+		WHERE method_id = $1
+		ORDER BY version_id DESC`
+
+	rows, err := r.db.Query(context.Background(), query, methodID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows[PaymentMethodVersion](rows, pgx.RowToStructByName)
+}
+
+// recordPaymentMethodVersion snapshots the current row of the method.
+func recordPaymentMethodVersion(tx pgx.Tx, methodID int, actorID int) (int, error) {
+	query := `
+		INSERT INTO payment_method_versions (method_id, method_name, method_type, is_active,
+			min_amount, max_amount, fee_percentage, created_by, created_at)
+		SELECT method_id, method_name, method_type, is_active,
+			min_amount, max_amount, fee_percentage, $2, $3
+		FROM payment_methods WHERE method_id = $1
+		RETURNING version_id`
+
+	var versionID int
+	err := tx.QueryRow(context.Background(), query, methodID, actorID, time.Now()).Scan(&versionID)
+	return versionID, err
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+type RefreshToken struct {
+	TokenID   int        `json:"token_id" db:"token_id"`
+	UserID    int        `json:"user_id" db:"user_id"`
+	SessionID *int       `json:"session_id" db:"session_id"`
+	FamilyID  string     `json:"family_id" db:"family_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+type RefreshTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRefreshTokenRepository(db *pgxpool.Pool) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func (r *RefreshTokenRepository) CreateRefreshToken(userID int, sessionID int, familyID string, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, session_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.Exec(context.Background(), query, userID, sessionID, familyID, tokenHash, expiresAt, time.Now())
+	return err
+}
+
+// RotateRefreshToken consumes the refresh token identified by oldHash, stores
+// newHash in the same family and returns the consumed token. Presenting a
+// token that was already rotated or revoked revokes the whole family and
+// returns ErrRefreshTokenReused. Unknown or expired tokens return pgx.ErrNoRows.
+func (r *RefreshTokenRepository) RotateRefreshToken(oldHash string, newHash string, expiresAt time.Time) (*RefreshToken, error) {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	row, err := tx.Query(context.Background(), `
+		SELECT token_id, user_id, session_id, family_id, token_hash, expires_at, created_at, used_at, revoked_at
+		FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, oldHash)
+	if err != nil {
+		return nil, err
+	}
+	token, err := pgx.CollectOneRow[RefreshToken](row, pgx.RowToStructByName)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.UsedAt != nil || token.RevokedAt != nil {
+		_, err = tx.Exec(context.Background(), `
+			UPDATE refresh_tokens SET revoked_at = $1
+			WHERE family_id = $2 AND revoked_at IS NULL`,
+			time.Now(), token.FamilyID)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(context.Background()); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, pgx.ErrNoRows
+	}
+
+	_, err = tx.Exec(context.Background(),
+		`UPDATE refresh_tokens SET used_at = $1 WHERE token_id = $2`, time.Now(), token.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+		INSERT INTO refresh_tokens (user_id, session_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		token.UserID, token.SessionID, token.FamilyID, newHash, expiresAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, tx.Commit(context.Background())
+}
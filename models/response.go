@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type APIResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
@@ -8,19 +10,57 @@ type APIResponse struct {
 	Error   string      `json:"error,omitempty"`
 }
 
+// FieldErrors maps a request field to everything wrong with it.
+type FieldErrors map[string][]string
+
+func (e FieldErrors) Add(field string, messages ...string) {
+	if len(messages) > 0 {
+		e[field] = append(e[field], messages...)
+	}
+}
+
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	User         User   `json:"user"`
+}
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type TOTPSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type PinStatus struct {
+	Locked            bool       `json:"locked"`
+	LockedUntil       *time.Time `json:"locked_until,omitempty"`
+	RemainingAttempts int        `json:"remaining_attempts"`
 }
 
 type TransactionResponse struct {
-	TransactionID   int     `json:"transaction_id"`
-	TransactionType string  `json:"transaction_type"`
-	Amount          float64 `json:"amount"`
-	Fee             float64 `json:"fee"`
-	ReferenceNumber string  `json:"reference_number"`
-	Status          string  `json:"status"`
-	NewBalance      float64 `json:"new_balance"`
+	TransactionID   int    `json:"transaction_id"`
+	TransactionType string `json:"transaction_type"`
+	Amount          Money  `json:"amount"`
+	Fee             Money  `json:"fee"`
+	ReferenceNumber string `json:"reference_number"`
+	Status          string `json:"status"`
+	NewBalance      Money  `json:"new_balance"`
 }
 
 type Meta struct {
@@ -32,6 +72,41 @@ type Meta struct {
 	To          int `json:"to"`
 }
 
+func NewMeta(page int, perPage int, total int) *Meta {
+	lastPage := (total + perPage - 1) / perPage
+	if lastPage == 0 {
+		lastPage = 1
+	}
+
+	meta := &Meta{
+		CurrentPage: page,
+		LastPage:    lastPage,
+		PerPage:     perPage,
+		Total:       total,
+	}
+	if total > 0 {
+		meta.From = (page-1)*perPage + 1
+		meta.To = min(page*perPage, total)
+		if meta.From > total {
+			meta.From, meta.To = 0, 0
+		}
+	}
+	return meta
+}
+
+type AdminUserDetail struct {
+	User               User          `json:"user"`
+	RecentTransactions []Transaction `json:"recent_transactions"`
+}
+
+// AccountExport is everything a user can download about their account.
+type AccountExport struct {
+	ExportedAt   time.Time     `json:"exported_at"`
+	Profile      User          `json:"profile"`
+	Contacts     []Contact     `json:"contacts"`
+	Transactions []Transaction `json:"transactions"`
+}
+
 type PaginatedResponse struct {
 	Data []interface{} `json:"data"`
 	Meta Meta          `json:"meta"`
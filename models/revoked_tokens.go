@@ -0,0 +1,101 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// notRevokedTTL is how long a jti found not to be revoked is trusted without
+// asking the database again. A revocation made on another instance is seen
+// within this window, one made on this instance at once.
+const notRevokedTTL = 30 * time.Second
+
+// revokedCache remembers the outcome of revocation lookups, revoked jtis
+// until the token would have expired anyway and valid ones for notRevokedTTL,
+// so most requests never reach the database.
+var revokedCache = struct {
+	sync.RWMutex
+	entries  map[string]revocation
+	prunedAt time.Time
+}{entries: map[string]revocation{}}
+
+type revocation struct {
+	revoked bool
+	until   time.Time
+}
+
+type RevokedTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRevokedTokenRepository(db *pgxpool.Pool) *RevokedTokenRepository {
+	return &RevokedTokenRepository{db: db}
+}
+
+func (r *RevokedTokenRepository) RevokeToken(jti string, userID int, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at, revoked_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (jti) DO NOTHING`
+
+	_, err := r.db.Exec(context.Background(), query, jti, userID, expiresAt, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Expired entries are useless, clean them up while we are here
+	r.db.Exec(context.Background(), `DELETE FROM revoked_tokens WHERE expires_at < $1`, time.Now())
+
+	cacheRevocation(jti, true, expiresAt)
+	return nil
+}
+
+// IsRevoked reports whether the token with this jti, which expires at
+// expiresAt, has been revoked.
+func (r *RevokedTokenRepository) IsRevoked(jti string, expiresAt time.Time) (bool, error) {
+	revokedCache.RLock()
+	cached, ok := revokedCache.entries[jti]
+	revokedCache.RUnlock()
+	if ok && time.Now().Before(cached.until) {
+		return cached.revoked, nil
+	}
+
+	var exists bool
+	err := r.db.QueryRow(context.Background(),
+		`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	if exists {
+		cacheRevocation(jti, true, expiresAt)
+	} else {
+		until := time.Now().Add(notRevokedTTL)
+		if expiresAt.Before(until) {
+			until = expiresAt
+		}
+		cacheRevocation(jti, false, until)
+	}
+	return exists, nil
+}
+
+func cacheRevocation(jti string, revoked bool, until time.Time) {
+	revokedCache.Lock()
+	defer revokedCache.Unlock()
+
+	// Every valid token passes through here, so sweep out stale entries
+	// now and then rather than on each write
+	now := time.Now()
+	if now.Sub(revokedCache.prunedAt) > notRevokedTTL {
+		for key, entry := range revokedCache.entries {
+			if now.After(entry.until) {
+				delete(revokedCache.entries, key)
+			}
+		}
+		revokedCache.prunedAt = now
+	}
+	revokedCache.entries[jti] = revocation{revoked: revoked, until: until}
+}
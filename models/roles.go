@@ -0,0 +1,45 @@
+package models
+
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+const (
+	PermissionUsersRead            = "users:read"
+	PermissionUsersManage          = "users:manage"
+	PermissionSessionsRevoke       = "sessions:revoke"
+	PermissionPinUnlock            = "pin:unlock"
+	PermissionPaymentMethodsManage = "payment_methods:manage"
+	PermissionAuditRead            = "audit:read"
+	PermissionSystemRead           = "system:read"
+)
+
+var rolePermissions = map[string][]string{
+	RoleUser: {},
+	RoleSupport: {
+		PermissionUsersRead,
+		PermissionSessionsRevoke,
+		PermissionPinUnlock,
+	},
+	RoleAdmin: {
+		PermissionUsersRead,
+		PermissionUsersManage,
+		PermissionSessionsRevoke,
+		PermissionPinUnlock,
+		PermissionPaymentMethodsManage,
+		PermissionAuditRead,
+		PermissionSystemRead,
+	},
+}
+
+// PermissionsForRole returns the permissions granted to role, none if unknown.
+func PermissionsForRole(role string) []string {
+	return append([]string{}, rolePermissions[role]...)
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Session struct {
+	SessionID   int        `json:"session_id" db:"session_id"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	UserAgent   string     `json:"user_agent" db:"user_agent"`
+	IPAddress   string     `json:"ip_address" db:"ip_address"`
+	DeviceLabel string     `json:"device_label" db:"device_label"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	IsCurrent   bool       `json:"is_current" db:"-"`
+}
+
+type SessionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) CreateSession(session *Session) error {
+	query := `
+		INSERT INTO sessions (user_id, user_agent, ip_address, device_label, created_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING session_id`
+
+	return r.db.QueryRow(context.Background(), query,
+		session.UserID,
+		session.UserAgent,
+		session.IPAddress,
+		session.DeviceLabel,
+		session.CreatedAt,
+		session.LastSeenAt).
+		Scan(&session.SessionID)
+}
+
+func (r *SessionRepository) GetActiveSessionsByUserID(userID int) ([]Session, error) {
+	query := `
+		SELECT session_id, user_id, user_agent, ip_address, device_label,
+			created_at, last_seen_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows[Session](rows, pgx.RowToStructByName)
+}
+
+// TouchSession bumps last_seen_at and returns pgx.ErrNoRows when the session
+// has been revoked or does not belong to the user.
+func (r *SessionRepository) TouchSession(sessionID int, userID int) error {
+	query := `
+		UPDATE sessions SET last_seen_at = $1
+		WHERE session_id = $2 AND user_id = $3 AND revoked_at IS NULL`
+
+	tag, err := r.db.Exec(context.Background(), query, time.Now(), sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeSession kills a session together with its refresh tokens.
+// Returns pgx.ErrNoRows when no active session matches.
+func (r *SessionRepository) RevokeSession(sessionID int, userID int) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	tag, err := tx.Exec(context.Background(), `
+		UPDATE sessions SET revoked_at = $1
+		WHERE session_id = $2 AND user_id = $3 AND revoked_at IS NULL`,
+		time.Now(), sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	_, err = tx.Exec(context.Background(), `
+		UPDATE refresh_tokens SET revoked_at = $1
+		WHERE session_id = $2 AND revoked_at IS NULL`,
+		time.Now(), sessionID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// RevokeAllUserSessions signs the user out everywhere: every session and
+// refresh token is revoked and the token version bump kills access tokens.
+func (r *SessionRepository) RevokeAllUserSessions(userID int) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := revokeUserSessions(tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// revokeUserSessions bumps the token version and revokes every session and
+// refresh token of the user inside tx.
+func revokeUserSessions(tx pgx.Tx, userID int) error {
+	_, err := tx.Exec(context.Background(),
+		`UPDATE users SET token_version = token_version + 1, updated_at = $1 WHERE user_id = $2`,
+		time.Now(), userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(),
+		`UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`,
+		time.Now(), userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(),
+		`UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`,
+		time.Now(), userID)
+	return err
+}
+
+// RevokeOtherSessions kills every session of the user except keepSessionID,
+// together with their refresh tokens.
+func (r *SessionRepository) RevokeOtherSessions(userID int, keepSessionID int) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), `
+		UPDATE sessions SET revoked_at = $1
+		WHERE user_id = $2 AND session_id <> $3 AND revoked_at IS NULL`,
+		time.Now(), userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+		UPDATE refresh_tokens SET revoked_at = $1
+		WHERE user_id = $2 AND session_id IS DISTINCT FROM $3 AND revoked_at IS NULL`,
+		time.Now(), userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
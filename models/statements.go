@@ -0,0 +1,107 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StatementLine is one balance change of the user, with the balance it left.
+type StatementLine struct {
+	HistoryID       int    `json:"history_id" db:"history_id"`
+	TransactionID   int    `json:"transaction_id" db:"transaction_id"`
+	TransactionType string `json:"transaction_type" db:"transaction_type"`
+	ReferenceNumber string `json:"reference_number" db:"reference_number"`
+	Summary         string `json:"summary" db:"transaction_summary"`
+	// Counterparty is the other user of a transfer as they are named now,
+	// nil for top-ups.
+	Counterparty  *string   `json:"counterparty" db:"counterparty"`
+	Change        Money     `json:"change" db:"change"`
+	BalanceBefore Money     `json:"balance_before" db:"balance_before"`
+	BalanceAfter  Money     `json:"balance_after" db:"balance_after"`
+	RecordedAt    time.Time `json:"recorded_at" db:"recorded_at"`
+}
+
+type Statement struct {
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance Money           `json:"opening_balance"`
+	ClosingBalance Money           `json:"closing_balance"`
+	TotalIn        Money           `json:"total_in"`
+	TotalOut       Money           `json:"total_out"`
+	Lines          []StatementLine `json:"lines"`
+}
+
+// newStatement totals the lines of [from, to) on top of the opening balance.
+func newStatement(from, to time.Time, opening Money, lines []StatementLine) *Statement {
+	statement := &Statement{
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		ClosingBalance: opening,
+		Lines:          lines,
+	}
+	if statement.Lines == nil {
+		statement.Lines = []StatementLine{}
+	}
+
+	for _, line := range lines {
+		if line.Change > 0 {
+			statement.TotalIn += line.Change
+		} else {
+			statement.TotalOut -= line.Change
+		}
+		statement.ClosingBalance = line.BalanceAfter
+	}
+
+	return statement
+}
+
+// GetStatement lists the user's balance changes recorded in [from, to),
+// oldest first, with opening and closing balances. The opening balance is
+// what the last change before from left, else what the first change since
+// started from (balances older than the history), else the current balance.
+func (r *TransactionRepository) GetStatement(userID int, from time.Time, to time.Time) (*Statement, error) {
+	tx, err := r.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	var opening Money
+	err = tx.QueryRow(context.Background(), `
+		SELECT COALESCE(
+			(SELECT balance_after FROM transaction_history
+			WHERE user_id = $1 AND recorded_at < $2
+			ORDER BY recorded_at DESC, history_id DESC LIMIT 1),
+			(SELECT balance_before FROM transaction_history
+			WHERE user_id = $1 AND recorded_at >= $2
+			ORDER BY recorded_at, history_id LIMIT 1),
+			balance)
+		FROM users WHERE user_id = $1`, userID, from).Scan(&opening)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(context.Background(), `
+		SELECT h.history_id, h.transaction_id, t.transaction_type, t.reference_number,
+			h.transaction_summary, cp.full_name AS counterparty,
+			h.balance_after - h.balance_before AS change,
+			h.balance_before, h.balance_after, h.recorded_at
+		FROM transaction_history h
+		JOIN transactions t ON t.transaction_id = h.transaction_id
+		LEFT JOIN users cp ON cp.user_id = CASE
+			WHEN t.sender_id = h.user_id THEN t.receiver_id ELSE t.sender_id END
+		WHERE h.user_id = $1 AND h.recorded_at >= $2 AND h.recorded_at < $3
+		ORDER BY h.recorded_at, h.history_id`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := pgx.CollectRows[StatementLine](rows, pgx.RowToStructByName)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStatement(from, to, opening, lines), nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// The stores below are what the auth middleware and the transaction handlers
+// need from storage. The repositories are the Postgres implementations,
+// MemoryStore the one for handler tests. The remaining auth flows (email
+// verification, password reset, OTP, MFA, refresh tokens and login
+// failures) still use their repositories directly and need Postgres.
+
+// UserStore is what the controllers need from user storage.
+type UserStore interface {
+	CreateUser(user *User) error
+	GetUserByEmail(email string) (*User, error)
+	GetUserByPhone(phone string) (*User, error)
+	GetUserByID(userID int) (*User, error)
+	GetAnyUserByID(userID int) (*User, error)
+	SearchUsers(keyword string, limit int, offset int) ([]User, int, error)
+	UpdateLastLogin(userID int) error
+	MarkPhoneVerified(userID int) error
+	ReservePinAttempt(userID int, maxAttempts int, lockUntil time.Time) (int, *time.Time, error)
+	ResetPinFailures(userID int) error
+	UpdatePin(userID int, pinHash string) error
+	UpdateProfile(user *User) error
+	UpdatePassword(userID int, passwordHash string) error
+	RehashPassword(userID int, oldHash string, newHash string) error
+	RehashPin(userID int, oldHash string, newHash string) error
+	CloseAccount(userID int) error
+}
+
+// TransactionStore is what the controllers need from transaction storage.
+type TransactionStore interface {
+	GetTransactionsByUserID(userID int, limit int) ([]Transaction, error)
+	GetAllTransactionsByUserID(userID int) ([]Transaction, error)
+	ProcessTransfer(senderID int, receiverID int, transactionType string, amount Money, fee Money, description string, referenceNumber string, status string) (*TransactionResponse, error)
+	ProcessTopup(topup *Transaction) (*TransactionResponse, error)
+	GetStatement(userID int, from time.Time, to time.Time) (*Statement, error)
+	GetPaymentMethodByID(methodID int) (*PaymentMethod, error)
+}
+
+// SessionStore is what the auth middleware and session endpoints need.
+type SessionStore interface {
+	CreateSession(session *Session) error
+	GetActiveSessionsByUserID(userID int) ([]Session, error)
+	TouchSession(sessionID int, userID int) error
+	RevokeSession(sessionID int, userID int) error
+	RevokeAllUserSessions(userID int) error
+	RevokeOtherSessions(userID int, keepSessionID int) error
+}
+
+// RevocationStore tracks access tokens revoked before they expire.
+type RevocationStore interface {
+	RevokeToken(jti string, userID int, expiresAt time.Time) error
+	IsRevoked(jti string, expiresAt time.Time) (bool, error)
+}
+
+var (
+	_ UserStore        = (*UserRepository)(nil)
+	_ TransactionStore = (*TransactionRepository)(nil)
+	_ SessionStore     = (*SessionRepository)(nil)
+	_ RevocationStore  = (*RevokedTokenRepository)(nil)
+	_ UserStore        = (*MemoryStore)(nil)
+	_ TransactionStore = (*MemoryStore)(nil)
+	_ SessionStore     = (*MemoryStore)(nil)
+	_ RevocationStore  = (*MemoryStore)(nil)
+)
@@ -1,12 +1,12 @@
 package models
 
 import (
-	"backend-ewallet/utils"
 	"context"
 	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Transaction struct {
@@ -15,8 +15,9 @@ type Transaction struct {
 	ReceiverID      *int       `json:"receiver_id" db:"receiver_id"`
 	TransactionType string     `json:"transaction_type" db:"transaction_type"`
 	PaymentMethodID *int       `json:"payment_method_id" db:"method_id"`
-	Amount          float64    `json:"amount" db:"amount"`
-	Fee             float64    `json:"fee" db:"fee"`
+	MethodVersionID *int       `json:"method_version_id" db:"method_version_id"`
+	Amount          Money      `json:"amount" db:"amount"`
+	Fee             Money      `json:"fee" db:"fee"`
 	Description     string     `json:"description" db:"description"`
 	ReferenceNumber string     `json:"reference_number" db:"reference_number"`
 	Status          string     `json:"status" db:"status"`
@@ -24,195 +25,228 @@ type Transaction struct {
 	CompletedAt     *time.Time `json:"completed_at" db:"completed_at"`
 }
 
-type PaymentMethod struct {
-	MethodID      int     `json:"method_id" db:"method_id"`
-	MethodName    string  `json:"method_name" db:"method_name"`
-	MethodType    string  `json:"method_type" db:"method_type"`
-	IsActive      bool    `json:"is_active" db:"is_active"`
-	MinAmount     float64 `json:"min_amount" db:"min_amount"`
-	MaxAmount     float64 `json:"max_amount" db:"max_amount"`
-	FeePercentage float64 `json:"fee_percentage" db:"fee_percentage"`
-}
+const transactionColumns = `transaction_id, sender_id, receiver_id, transaction_type, method_id, method_version_id,
+	amount, fee, description, reference_number, status, created_at, completed_at`
 
 type TransactionHistory struct {
-	ID                 int
-	UserID             int
-	TransactionID      int
-	TransactionSummary string
-	BalanceBefore      float64
-	BalanceAfter       float64
-	RecordedAt         time.Time
+	ID                 int       `json:"history_id" db:"history_id"`
+	UserID             int       `json:"user_id" db:"user_id"`
+	TransactionID      int       `json:"transaction_id" db:"transaction_id"`
+	TransactionSummary string    `json:"transaction_summary" db:"transaction_summary"`
+	BalanceBefore      Money     `json:"balance_before" db:"balance_before"`
+	BalanceAfter       Money     `json:"balance_after" db:"balance_after"`
+	RecordedAt         time.Time `json:"recorded_at" db:"recorded_at"`
 }
 
-type TransactionRepository struct{}
+type TransactionRepository struct {
+	db *pgxpool.Pool
+}
 
-func NewTransactionRepository() *TransactionRepository {
-	return &TransactionRepository{}
+func NewTransactionRepository(db *pgxpool.Pool) *TransactionRepository {
+	return &TransactionRepository{db: db}
 }
 
-func (r *TransactionRepository) CreateTransaction(tx *Transaction) error {
-	conn, err := utils.ConnectDB()
+func (r *TransactionRepository) GetTransactionsByUserID(userID int, limit int) ([]Transaction, error) {
+	query := `
+	SELECT ` + transactionColumns + `
+	FROM transactions 
+	WHERE sender_id = $1 OR receiver_id = $1
+	ORDER BY created_at DESC
+	LIMIT $2`
+
+	rows, err := r.db.Query(context.Background(), query, userID, limit)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer utils.CloseDB(conn)
-
-	if tx.TransactionType == "transfer" {
-		query := `
-			INSERT INTO transactions (sender_id, receiver_id, transaction_type, amount, fee,
-				description, reference_number, status, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-			RETURNING transaction_id`
 
-		err = conn.QueryRow(context.Background(), query,
-			tx.SenderID, tx.ReceiverID, tx.TransactionType, tx.Amount, tx.Fee,
-			tx.Description, tx.Status, tx.CreatedAt).
-			Scan(&tx.TransactionID)
-	}
+	return pgx.CollectRows[Transaction](rows, pgx.RowToStructByName)
+}
 
-	if tx.TransactionType == "topup" {
-		query := `
-			INSERT INTO transactions (receiver_id, method_id, transaction_type, amount, fee,
-				description, reference_number, status)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-			RETURNING transaction_id`
+// GetAllTransactionsByUserID returns the complete history, oldest first.
+func (r *TransactionRepository) GetAllTransactionsByUserID(userID int) ([]Transaction, error) {
+	query := `
+	SELECT ` + transactionColumns + `
+	FROM transactions
+	WHERE sender_id = $1 OR receiver_id = $1
+	ORDER BY created_at, transaction_id`
 
-		err = conn.QueryRow(context.Background(), query,
-			tx.ReceiverID, tx.PaymentMethodID, tx.TransactionType, tx.Amount, tx.Fee,
-			tx.Description, tx.ReferenceNumber, tx.Status).
-			Scan(&tx.TransactionID)
+	rows, err := r.db.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Println(err)
-	return err
+	return pgx.CollectRows[Transaction](rows, pgx.RowToStructByName)
 }
 
-func (r *TransactionRepository) UpdateTransactionStatus(transactionID int, status string) error {
-	conn, err := utils.ConnectDB()
+// ProcessTransfer debits the sender by amount plus fee, credits the receiver
+// with amount and books the fee as revenue, all in one database transaction.
+// An insufficient balance is reported as pgx.ErrNoRows.
+func (r *TransactionRepository) ProcessTransfer(senderID int, receiverID int, transactionType string, amount Money, fee Money, description string, referenceNumber string, status string) (*TransactionResponse, error) {
+	tx, err := r.db.Begin(context.Background())
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer utils.CloseDB(conn)
-
-	var query string
-	var args []interface{}
+	defer tx.Rollback(context.Background())
 
-	if status == "completed" {
-		query = `UPDATE transactions SET status = $1, completed_at = $2 WHERE transaction_id = $3`
-		args = []interface{}{status, time.Now(), transactionID}
-	} else {
-		query = `UPDATE transactions SET status = $1 WHERE transaction_id = $2`
-		args = []interface{}{status, transactionID}
+	users, err := lockBalances(tx, senderID, receiverID)
+	if err != nil {
+		return nil, err
+	}
+	sender, ok := users[senderID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	receiver, ok := users[receiverID]
+	if !ok || !receiver.isActive {
+		return nil, fmt.Errorf("receiver %d not found", receiverID)
 	}
 
-	_, err = conn.Exec(context.Background(), query, args...)
-	return err
-}
+	totalAmount := amount + fee
+	if sender.balance < totalAmount {
+		return nil, pgx.ErrNoRows // Use this to indicate insufficient balance
+	}
 
-func (r *TransactionRepository) GetTransactionsByUserID(userID int, limit int) ([]Transaction, error) {
-	conn, err := utils.ConnectDB()
+	// Create transaction record
+	var transactionID int
+	err = tx.QueryRow(context.Background(), `
+	INSERT INTO transactions (sender_id, receiver_id, transaction_type, amount, fee,
+	description, reference_number, status, created_at, completed_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING transaction_id`,
+		senderID, receiverID, transactionType, amount, fee, description,
+		referenceNumber, status, time.Now(), time.Now()).
+		Scan(&transactionID)
 	if err != nil {
-		return []Transaction{}, err
+		return nil, err
 	}
-	defer utils.CloseDB(conn)
-
-	query := `
-	SELECT transaction_id, sender_id, receiver_id, transaction_type, method_id,
-	amount, fee, description, reference_number, status, created_at, completed_at
-	FROM transactions 
-	WHERE sender_id = $1 OR receiver_id = $1
-	ORDER BY created_at DESC
-	LIMIT $2`
 
-	rows, err := conn.Query(context.Background(), query, userID, limit)
+	senderAccount, err := userAccountID(tx, senderID)
+	if err != nil {
+		return nil, err
+	}
+	receiverAccount, err := userAccountID(tx, receiverID)
+	if err != nil {
+		return nil, err
+	}
+	feeAccount, err := systemAccountID(tx, AccountFeeRevenue)
 	if err != nil {
 		return nil, err
 	}
 
-	transactions, err := pgx.CollectRows[Transaction](rows, pgx.RowToStructByName)
+	newBalances, err := postEntry(tx, &transactionID, "Transfer "+referenceNumber, []Posting{
+		{AccountID: senderAccount, Amount: -totalAmount, Memo: transferMemo("Transfer sent", description)},
+		{AccountID: receiverAccount, Amount: amount, Memo: transferMemo("Transfer received", description)},
+		{AccountID: feeAccount, Amount: fee},
+	})
 	if err != nil {
-		fmt.Println(err)
 		return nil, err
 	}
 
-	return transactions, nil
+	response := &TransactionResponse{
+		TransactionID:   transactionID,
+		ReferenceNumber: referenceNumber,
+		TransactionType: transactionType,
+		Amount:          amount,
+		Fee:             fee,
+		Status:          status,
+		NewBalance:      newBalances[senderID],
+	}
+
+	// Commit transaction
+	return response, tx.Commit(context.Background())
 }
 
-func (r *TransactionRepository) ProcessTransfer(senderID int, receiverID int, transactionType string, amount float64, fee float64, description string, referenceNumber string, status string) (*TransactionResponse, error) {
-	conn, err := utils.ConnectDB()
+// ProcessTopup records a completed top-up and books it in one database
+// transaction, under the user's row lock: the user is credited with the
+// amount, the fee goes to revenue and both are owed by the top-up clearing
+// account until the payment provider settles. topup gets its id, the
+// response carries the user's new balance. A user that no longer exists or
+// was deactivated is reported as pgx.ErrNoRows.
+func (r *TransactionRepository) ProcessTopup(topup *Transaction) (*TransactionResponse, error) {
+	tx, err := r.db.Begin(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	defer utils.CloseDB(conn)
+	defer tx.Rollback(context.Background())
 
-	tx, err := conn.Begin(context.Background())
+	userID := *topup.ReceiverID
+	users, err := lockBalances(tx, userID)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback(context.Background())
+	if user, ok := users[userID]; !ok || !user.isActive {
+		return nil, pgx.ErrNoRows
+	}
 
-	var senderBalance float64
-	err = tx.QueryRow(context.Background(), "SELECT balance FROM users WHERE user_id = $1", senderID).Scan(&senderBalance)
+	now := time.Now()
+	topup.CreatedAt = now
+	if topup.Status == "completed" {
+		topup.CompletedAt = &now
+	}
+	err = tx.QueryRow(context.Background(), `
+		INSERT INTO transactions (receiver_id, method_id, method_version_id, transaction_type, amount, fee,
+			description, reference_number, status, created_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING transaction_id`,
+		topup.ReceiverID, topup.PaymentMethodID, topup.MethodVersionID, topup.TransactionType, topup.Amount, topup.Fee,
+		topup.Description, topup.ReferenceNumber, topup.Status, topup.CreatedAt, topup.CompletedAt).
+		Scan(&topup.TransactionID)
 	if err != nil {
 		return nil, err
 	}
 
-	totalAmount := amount + fee
-	if senderBalance < totalAmount {
-		return nil, pgx.ErrNoRows // Use this to indicate insufficient balance
+	userAccount, err := userAccountID(tx, userID)
+	if err != nil {
+		return nil, err
 	}
-
-	newSenderBalance := senderBalance - totalAmount
-	_, err = tx.Exec(context.Background(), "UPDATE users SET balance = $1, updated_at = $2 WHERE user_id = $3",
-		newSenderBalance, time.Now(), senderID)
+	feeAccount, err := systemAccountID(tx, AccountFeeRevenue)
 	if err != nil {
 		return nil, err
 	}
-
-	_, err = tx.Exec(context.Background(), "UPDATE users SET balance = balance + $1, updated_at = $2 WHERE user_id = $3",
-		amount, time.Now(), receiverID)
-
+	clearingAccount, err := systemAccountID(tx, AccountTopupClearing)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create transaction record
-	_, err = tx.Exec(context.Background(), `
-	INSERT INTO transactions (sender_id, receiver_id, transaction_type, amount, fee,
-	description, reference_number, status, created_at, completed_at)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
-		senderID, receiverID, transactionType, amount, fee, description,
-		referenceNumber, status, time.Now(), time.Now())
-	fmt.Println(err)
+	balances, err := postEntry(tx, &topup.TransactionID, "Top up "+topup.ReferenceNumber, []Posting{
+		{AccountID: userAccount, Amount: topup.Amount, Memo: topup.Description},
+		{AccountID: feeAccount, Amount: topup.Fee},
+		{AccountID: clearingAccount, Amount: -(topup.Amount + topup.Fee)},
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	response := &TransactionResponse{
-		ReferenceNumber: referenceNumber,
-		TransactionType: transactionType,
-		Amount:          amount,
-		Fee:             fee,
-		Status:          status,
-		NewBalance:      senderBalance,
+		TransactionID:   topup.TransactionID,
+		ReferenceNumber: topup.ReferenceNumber,
+		TransactionType: topup.TransactionType,
+		Amount:          topup.Amount,
+		Fee:             topup.Fee,
+		Status:          topup.Status,
+		NewBalance:      balances[userID],
 	}
 
-	// Commit transaction
 	return response, tx.Commit(context.Background())
 }
 
-func (r *TransactionRepository) GetPaymentMethodByID(methodID int) (*PaymentMethod, error) {
-	conn, err := utils.ConnectDB()
-	if err != nil {
-		return nil, err
+// transferMemo is how a transfer reads in a user's history. It never names
+// the counterparty: statements look the other user up when they are read, so
+// a closed account's name doesn't live on in other users' history.
+func transferMemo(direction string, description string) string {
+	memo := direction
+	if description != "" {
+		memo += ": " + description
 	}
-	defer utils.CloseDB(conn)
+	return memo
+}
 
+func (r *TransactionRepository) GetPaymentMethodByID(methodID int) (*PaymentMethod, error) {
 	query := `
-		SELECT method_id, method_name, method_type, is_active, min_amount, max_amount, fee_percentage
-		FROM payment_methods WHERE method_id = $1 AND is_active = true`
+		SELECT ` + paymentMethodColumns + `
+		FROM payment_methods pm WHERE method_id = $1 AND is_active = true`
 
-	row, err := conn.Query(context.Background(), query, methodID)
+	row, err := r.db.Query(context.Background(), query, methodID)
 	if err != nil {
 		return nil, err
 	}
@@ -223,4 +257,4 @@ func (r *TransactionRepository) GetPaymentMethodByID(methodID int) (*PaymentMeth
 	}
 
 	return &method, err
-}
\ No newline at end of file
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"backend-ewallet/migrations"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testDB connects to TEST_DATABASE_URL and migrates it, skipping the test
+// when it is unset. The tests add their own users and never clean up, so
+// point it at a database used for nothing else.
+func testDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// Concurrent top-ups for one user must serialize on the user's row lock:
+// none may be lost, and every history row must start where the previous
+// one ended.
+func TestProcessTopupConcurrent(t *testing.T) {
+	db := testDB(t)
+	users := NewUserRepository(db)
+	transactions := NewTransactionRepository(db)
+
+	run := time.Now().UnixNano()
+	user := &User{
+		Email:              fmt.Sprintf("topup-%d@example.com", run),
+		Phone:              fmt.Sprintf("+1%d", run%1e12),
+		FullName:           "Topup Test",
+		RegistrationStatus: "completed",
+		IsActive:           true,
+		Role:               RoleUser,
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
+	}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	topup := func(reference string, amount Money) error {
+		_, err := transactions.ProcessTopup(&Transaction{
+			ReceiverID:      &user.UserID,
+			TransactionType: "topup",
+			Amount:          amount,
+			Fee:             amount.Percent(MustParsePercentage("1"), RoundHalfUp),
+			Description:     "Top up",
+			ReferenceNumber: reference,
+			Status:          "completed",
+		})
+		return err
+	}
+	if err := topup(fmt.Sprintf("TOPUP-%d-0", run), 5000); err != nil {
+		t.Fatal(err)
+	}
+	start, err := users.GetUserByID(user.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	var sum Money
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 1; i <= n; i++ {
+		amount := Money(i * 1000)
+		sum += amount
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- topup(fmt.Sprintf("TOPUP-%d-%d", run, i), amount)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	final, err := users.GetUserByID(user.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.Balance != start.Balance+sum {
+		t.Errorf("balance = %s, want %s + %s", final.Balance, start.Balance, sum)
+	}
+
+	var count int
+	err = db.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM transactions WHERE receiver_id = $1 AND reference_number <> $2`,
+		user.UserID, fmt.Sprintf("TOPUP-%d-0", run)).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != n {
+		t.Errorf("got %d transactions, want %d", count, n)
+	}
+
+	rows, err := db.Query(context.Background(), `
+		SELECT balance_before, balance_after FROM transaction_history
+		WHERE user_id = $1 ORDER BY history_id`, user.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var history [][2]Money
+	for rows.Next() {
+		var before, after Money
+		if err := rows.Scan(&before, &after); err != nil {
+			t.Fatal(err)
+		}
+		history = append(history, [2]Money{before, after})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first row is the top-up that set the starting balance
+	if len(history) != n+1 {
+		t.Fatalf("got %d history rows, want %d", len(history), n+1)
+	}
+	if history[0][0] != 0 || history[0][1] != start.Balance {
+		t.Errorf("first history row = %v, want [0 %s]", history[0], start.Balance)
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i][0] != history[i-1][1] {
+			t.Errorf("history row %d starts at %s, the previous one ended at %s", i, history[i][0], history[i-1][1])
+		}
+	}
+	if last := history[len(history)-1][1]; last != final.Balance {
+		t.Errorf("last history row ends at %s, balance is %s", last, final.Balance)
+	}
+
+	report, err := CheckLedger(db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Unbalanced) != 0 {
+		t.Errorf("unbalanced entries: %v", report.Unbalanced)
+	}
+}
@@ -1,13 +1,16 @@
 package models
 
 import (
-	"backend-ewallet/utils"
 	"context"
+	"errors"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var ErrPinLocked = errors.New("pin is locked")
+
 type User struct {
 	UserID             int        `json:"user_id" db:"user_id"`
 	Email              string     `json:"email" db:"email"`
@@ -15,44 +18,52 @@ type User struct {
 	FullName           string     `json:"full_name" db:"full_name"`
 	PasswordHash       string     `json:"-" db:"password_hash"`
 	PinHash            string     `json:"-" db:"pin_hash"`
-	Balance            float64    `json:"balance" db:"balance"`
+	Balance            Money      `json:"balance" db:"balance"`
 	RegistrationStatus string     `json:"registration_status" db:"registration_status"`
+	SuspendedFrom      *string    `json:"-" db:"suspended_from"`
 	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
 	LastLogin          *time.Time `json:"last_login" db:"last_login"`
 	IsActive           bool       `json:"is_active" db:"is_active"`
+	TokenVersion       int        `json:"-" db:"token_version"`
+	PhoneVerifiedAt    *time.Time `json:"phone_verified_at" db:"phone_verified_at"`
+	PinFailedAttempts  int        `json:"-" db:"pin_failed_attempts"`
+	PinLockedUntil     *time.Time `json:"pin_locked_until" db:"pin_locked_until"`
+	Role               string     `json:"role" db:"role"`
+	ClosedAt           *time.Time `json:"closed_at,omitempty" db:"closed_at"`
 }
 
 type Contact struct {
 	ContactID     int       `json:"contact_id" db:"contact_id"`
 	UserID        int       `json:"user_id" db:"user_id"`
-	ContactUserID int       `json:"contact_user_id" db:"contact_user_id"`
+	ContactUserID *int      `json:"contact_user_id" db:"contact_user_id"`
 	ContactName   string    `json:"contact_name" db:"contact_name"`
 	ContactPhone  string    `json:"contact_phone" db:"contact_phone"`
 	IsFavorite    bool      `json:"is_favorite" db:"is_favorite"`
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 }
 
-type UserRepository struct{}
+const userColumns = `user_id, email, phone, full_name, password_hash, pin_hash, balance,
+	registration_status, suspended_from, created_at, updated_at, last_login,
+	is_active, token_version, phone_verified_at,
+	pin_failed_attempts, pin_locked_until, role, closed_at`
 
-func NewUserRepository() *UserRepository {
-	return &UserRepository{}
+type UserRepository struct {
+	db *pgxpool.Pool
 }
 
-func (r *UserRepository) CreateUser(user *User) error {
-	conn, err := utils.ConnectDB()
-	if err != nil {
-		return err
-	}
-	defer utils.CloseDB(conn)
+func NewUserRepository(db *pgxpool.Pool) *UserRepository {
+	return &UserRepository{db: db}
+}
 
+func (r *UserRepository) CreateUser(user *User) error {
 	query := `
 		INSERT INTO users (email, phone, full_name, password_hash, pin_hash, balance, 
-			registration_status, created_at, updated_at, is_active)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			registration_status, created_at, updated_at, is_active, role)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING user_id`
 
-	err = conn.QueryRow(context.Background(), query,
+	err := r.db.QueryRow(context.Background(), query,
 		user.Email,
 		user.Phone,
 		user.FullName,
@@ -62,26 +73,19 @@ func (r *UserRepository) CreateUser(user *User) error {
 		user.RegistrationStatus,
 		user.CreatedAt,
 		user.UpdatedAt,
-		user.IsActive).
+		user.IsActive,
+		user.Role).
 		Scan(&user.UserID)
 
 	return err
 }
 
 func (r *UserRepository) GetUserByEmail(email string) (*User, error) {
-	conn, err := utils.ConnectDB()
-	if err != nil {
-		return &User{}, err
-	}
-	defer utils.CloseDB(conn)
-
 	query := `
-		SELECT user_id, email, phone, full_name, password_hash, pin_hash, balance,
-			registration_status, created_at, updated_at, last_login,
-			is_active
+		SELECT ` + userColumns + `
 		FROM users WHERE email = $1 AND is_active = true`
 
-	row, err := conn.Query(context.Background(), query, email)
+	row, err := r.db.Query(context.Background(), query, email)
 	if err != nil {
 		return nil, err
 	}
@@ -94,19 +98,28 @@ func (r *UserRepository) GetUserByEmail(email string) (*User, error) {
 }
 
 func (r *UserRepository) GetUserByPhone(phone string) (*User, error) {
-	conn, err := utils.ConnectDB()
+	query := `
+		SELECT ` + userColumns + `
+		FROM users WHERE phone = $1 AND is_active = true`
+
+	row, err := r.db.Query(context.Background(), query, phone)
+	if err != nil {
+		return nil, err
+	}
+	user, err := pgx.CollectOneRow[User](row, pgx.RowToStructByName)
 	if err != nil {
-		return &User{}, err
+		return nil, err
 	}
-	defer utils.CloseDB(conn)
 
+	return &user, nil
+}
+
+func (r *UserRepository) GetUserByID(userID int) (*User, error) {
 	query := `
-		SELECT user_id, email, phone, full_name, password_hash, pin_hash, balance,
-			registration_status, created_at, updated_at, last_login,
-			is_active
-		FROM users WHERE phone = $1 AND is_active = true`
+		SELECT ` + userColumns + `
+		FROM users WHERE user_id = $1 AND is_active = true`
 
-	row, err := conn.Query(context.Background(), query, phone)
+	row, err := r.db.Query(context.Background(), query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -118,20 +131,136 @@ func (r *UserRepository) GetUserByPhone(phone string) (*User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) GetUserByID(userID int) (*User, error) {
-	conn, err := utils.ConnectDB()
+func (r *UserRepository) UpdateLastLogin(userID int) error {
+	query := `UPDATE users SET last_login = $1 WHERE user_id = $2`
+	_, err := r.db.Exec(context.Background(), query, time.Now(), userID)
+	return err
+}
+
+func (r *UserRepository) MarkPhoneVerified(userID int) error {
+	query := `UPDATE users SET phone_verified_at = $1, updated_at = $1 WHERE user_id = $2`
+	_, err := r.db.Exec(context.Background(), query, time.Now(), userID)
+	return err
+}
+
+// ReservePinAttempt counts a PIN attempt before the PIN is checked, so
+// parallel guesses can't all slip in under the lock. The attempt that
+// reaches maxAttempts locks the PIN until lockUntil and starts the counter
+// over; a correct PIN clears both through ResetPinFailures. While the PIN is
+// locked nothing is counted and ErrPinLocked comes back with the lock's end.
+func (r *UserRepository) ReservePinAttempt(userID int, maxAttempts int, lockUntil time.Time) (int, *time.Time, error) {
+	query := `
+		UPDATE users SET
+			pin_locked_until = CASE WHEN pin_failed_attempts + 1 >= $1 THEN $2 ELSE NULL END,
+			pin_failed_attempts = CASE WHEN pin_failed_attempts + 1 >= $1 THEN 0 ELSE pin_failed_attempts + 1 END
+		WHERE user_id = $3 AND (pin_locked_until IS NULL OR pin_locked_until <= $4)
+		RETURNING pin_failed_attempts, pin_locked_until`
+
+	var attempts int
+	var lockedUntil *time.Time
+	err := r.db.QueryRow(context.Background(), query, maxAttempts, lockUntil, userID, time.Now()).
+		Scan(&attempts, &lockedUntil)
+	if err != pgx.ErrNoRows {
+		return attempts, lockedUntil, err
+	}
+
+	err = r.db.QueryRow(context.Background(),
+		`SELECT pin_locked_until FROM users WHERE user_id = $1`, userID).Scan(&lockedUntil)
 	if err != nil {
-		return &User{}, err
+		return 0, nil, err
 	}
-	defer utils.CloseDB(conn)
+	return 0, lockedUntil, ErrPinLocked
+}
 
+func (r *UserRepository) ResetPinFailures(userID int) error {
+	query := `UPDATE users SET pin_failed_attempts = 0, pin_locked_until = NULL WHERE user_id = $1`
+	_, err := r.db.Exec(context.Background(), query, userID)
+	return err
+}
+
+// UpdatePin stores a new PIN hash and clears any PIN lock.
+func (r *UserRepository) UpdatePin(userID int, pinHash string) error {
 	query := `
-		SELECT user_id, email, phone, full_name, password_hash, pin_hash, balance,
-			registration_status, created_at, updated_at, last_login,
-			is_active
-		FROM users WHERE user_id = $1 AND is_active = true`
+		UPDATE users SET pin_hash = $1, pin_failed_attempts = 0, pin_locked_until = NULL, updated_at = $2
+		WHERE user_id = $3`
+	_, err := r.db.Exec(context.Background(), query, pinHash, time.Now(), userID)
+	return err
+}
+
+func (r *UserRepository) UpdateProfile(user *User) error {
+	user.UpdatedAt = time.Now()
+	query := `
+		UPDATE users SET full_name = $1, email = $2, phone = $3, registration_status = $4,
+			phone_verified_at = $5, updated_at = $6
+		WHERE user_id = $7`
+	_, err := r.db.Exec(context.Background(), query,
+		user.FullName,
+		user.Email,
+		user.Phone,
+		user.RegistrationStatus,
+		user.PhoneVerifiedAt,
+		user.UpdatedAt,
+		user.UserID)
+	return err
+}
+
+func (r *UserRepository) UpdatePassword(userID int, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $1, updated_at = $2 WHERE user_id = $3`
+	_, err := r.db.Exec(context.Background(), query, passwordHash, time.Now(), userID)
+	return err
+}
+
+// RehashPassword swaps in a hash of the same password made with the current
+// parameters, unless the password was changed in the meantime.
+func (r *UserRepository) RehashPassword(userID int, oldHash string, newHash string) error {
+	query := `UPDATE users SET password_hash = $1 WHERE user_id = $2 AND password_hash = $3`
+	_, err := r.db.Exec(context.Background(), query, newHash, userID, oldHash)
+	return err
+}
+
+// RehashPin is the PIN counterpart of RehashPassword.
+func (r *UserRepository) RehashPin(userID int, oldHash string, newHash string) error {
+	query := `UPDATE users SET pin_hash = $1 WHERE user_id = $2 AND pin_hash = $3`
+	_, err := r.db.Exec(context.Background(), query, newHash, userID, oldHash)
+	return err
+}
+
+// SearchUsers matches email, phone or full name, including inactive accounts.
+func (r *UserRepository) SearchUsers(keyword string, limit int, offset int) ([]User, int, error) {
+	filter := `
+		WHERE $1 = '' OR email ILIKE '%' || $1 || '%' OR phone ILIKE '%' || $1 || '%'
+			OR full_name ILIKE '%' || $1 || '%'`
+
+	var total int
+	err := r.db.QueryRow(context.Background(), `SELECT COUNT(*) FROM users`+filter, keyword).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(context.Background(), `
+		SELECT `+userColumns+`
+		FROM users`+filter+`
+		ORDER BY user_id
+		LIMIT $2 OFFSET $3`, keyword, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users, err := pgx.CollectRows[User](rows, pgx.RowToStructByName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// GetAnyUserByID is GetUserByID without the is_active filter, for back-office use.
+func (r *UserRepository) GetAnyUserByID(userID int) (*User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users WHERE user_id = $1`
 
-	row, err := conn.Query(context.Background(), query, userID)
+	row, err := r.db.Query(context.Background(), query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -143,27 +272,104 @@ func (r *UserRepository) GetUserByID(userID int) (*User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) UpdateUserBalance(userID int, newBalance float64) error {
-	conn, err := utils.ConnectDB()
+// SuspendUser moves the user from status to suspended, remembering status
+// so ReactivateUser restores exactly it. Sessions and tokens are revoked and
+// the audit entry is written in the same transaction. Returns pgx.ErrNoRows
+// when the user is no longer in status.
+func (r *UserRepository) SuspendUser(userID int, status string, entry *AuditLog) error {
+	tx, err := r.db.Begin(context.Background())
 	if err != nil {
 		return err
 	}
-	defer utils.CloseDB(conn)
+	defer tx.Rollback(context.Background())
 
-	query := `UPDATE users SET balance = $1, updated_at = $2 WHERE user_id = $3`
-	_, err = conn.Exec(context.Background(), query, newBalance, time.Now(), userID)
-	return err
+	tag, err := tx.Exec(context.Background(), `
+		UPDATE users SET registration_status = 'suspended', suspended_from = $1, updated_at = $2
+		WHERE user_id = $3 AND registration_status = $1 AND $1 IN ('pending', 'completed')`,
+		status, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := revokeUserSessions(tx, userID); err != nil {
+		return err
+	}
+	if err := insertAuditLog(tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
 }
 
-func (r *UserRepository) UpdateLastLogin(userID int) error {
-	conn, err := utils.ConnectDB()
+// ReactivateUser lifts a suspension, putting back status, the one the user
+// had before it, and writes the audit entry in the same transaction. Returns
+// pgx.ErrNoRows when the user is not suspended from status.
+func (r *UserRepository) ReactivateUser(userID int, status string, entry *AuditLog) error {
+	tx, err := r.db.Begin(context.Background())
 	if err != nil {
 		return err
 	}
-	defer utils.CloseDB(conn)
+	defer tx.Rollback(context.Background())
 
-	query := `UPDATE users SET last_login = $1 WHERE user_id = $2`
-	_, err = conn.Exec(context.Background(), query, time.Now(), userID)
-	return err
+	tag, err := tx.Exec(context.Background(), `
+		UPDATE users SET registration_status = $1, suspended_from = NULL, updated_at = $2
+		WHERE user_id = $3 AND registration_status = 'suspended'
+			AND COALESCE(suspended_from, 'pending') = $1`,
+		status, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := insertAuditLog(tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
+
+// ForceLogout revokes every session of the user and writes the audit entry
+// in the same transaction.
+func (r *UserRepository) ForceLogout(userID int, entry *AuditLog) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := revokeUserSessions(tx, userID); err != nil {
+		return err
+	}
+	if err := insertAuditLog(tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
 }
 
+// ResetPinLockout clears the user's PIN failures and lock and writes the
+// audit entry in the same transaction.
+func (r *UserRepository) ResetPinLockout(userID int, entry *AuditLog) error {
+	tx, err := r.db.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(),
+		`UPDATE users SET pin_failed_attempts = 0, pin_locked_until = NULL, updated_at = $1 WHERE user_id = $2`,
+		time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	if err := insertAuditLog(tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(context.Background())
+}
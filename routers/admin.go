@@ -0,0 +1,24 @@
+package routers
+
+import (
+	"backend-ewallet/controllers"
+	"backend-ewallet/middlewares"
+	"backend-ewallet/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func adminRouter(r *gin.RouterGroup, db *pgxpool.Pool) {
+	adminController := controllers.NewAdminController(db)
+	r.Use(authMiddleware(db))
+
+	r.GET("/users", middlewares.RequirePermission(models.PermissionUsersRead), adminController.SearchUsers)
+	r.GET("/users/:id", middlewares.RequirePermission(models.PermissionUsersRead), adminController.GetUser)
+	r.POST("/users/:id/suspend", middlewares.RequirePermission(models.PermissionUsersManage), adminController.SuspendUser)
+	r.POST("/users/:id/reactivate", middlewares.RequirePermission(models.PermissionUsersManage), adminController.ReactivateUser)
+	r.POST("/users/:id/force-logout", middlewares.RequirePermission(models.PermissionSessionsRevoke), adminController.ForceLogout)
+	r.POST("/users/:id/reset-pin-lockout", middlewares.RequirePermission(models.PermissionPinUnlock), adminController.ResetPinLockout)
+	r.GET("/audit-logs", middlewares.RequirePermission(models.PermissionAuditRead), adminController.GetAuditLogs)
+	r.GET("/health", middlewares.RequirePermission(models.PermissionSystemRead), controllers.NewHealthController(db).GetPoolHealth)
+}
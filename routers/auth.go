@@ -2,15 +2,33 @@ package routers
 
 import (
 	"backend-ewallet/controllers"
+	"backend-ewallet/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func authRouter(r *gin.RouterGroup) {
-	authController := controllers.NewAuthController()
+func authRouter(r *gin.RouterGroup, db *pgxpool.Pool) {
+	authController := controllers.NewAuthController(db, models.NewUserRepository(db))
+	auth := authMiddleware(db)
 
 	r.POST("/register", authController.Register)
 	r.POST("/login", authController.Login)
-	r.POST("/forgot-password")
-	r.POST("/reset-password")
+	r.POST("/refresh", authController.Refresh)
+	r.GET("/verify-email", authController.VerifyEmail)
+	r.POST("/resend-verification", auth, authController.ResendVerification)
+	r.POST("/phone/send-otp", auth, authController.SendPhoneOTP)
+	r.POST("/phone/verify", auth, authController.VerifyPhone)
+	r.POST("/otp/request", authController.RequestLoginOTP)
+	r.POST("/otp/login", authController.LoginWithOTP)
+	r.POST("/mfa/verify", authController.VerifyMFA)
+	r.POST("/mfa/totp/setup", auth, authController.SetupTOTP)
+	r.POST("/mfa/totp/confirm", auth, authController.ConfirmTOTP)
+	r.POST("/mfa/totp/disable", auth, authController.DisableTOTP)
+	r.POST("/forgot-password", authController.ForgotPassword)
+	r.POST("/reset-password", authController.ResetPassword)
+	r.POST("/logout", auth, authController.Logout)
+	r.POST("/logout-all", auth, authController.LogoutAll)
+	r.GET("/sessions", auth, authController.GetSessions)
+	r.DELETE("/sessions/:id", auth, authController.DeleteSession)
 }
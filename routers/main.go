@@ -1,10 +1,25 @@
 package routers
 
 import (
+	"backend-ewallet/controllers"
+	"backend-ewallet/middlewares"
+	"backend-ewallet/models"
+
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func CombineRouters(r *gin.Engine) {
-	authRouter(r.Group("/auth"))
-	transactionRouter(r.Group("/transactions"))
+func CombineRouters(r *gin.Engine, db *pgxpool.Pool) {
+	r.GET("/health", controllers.NewHealthController(db).GetHealth)
+	r.GET("/.well-known/jwks.json", controllers.GetJWKS)
+	authRouter(r.Group("/auth"), db)
+	transactionRouter(r.Group("/transactions"), db)
+	userRouter(r.Group("/users"), db)
+	paymentMethodRouter(r.Group("/payment-methods"), db)
+	adminRouter(r.Group("/admin"), db)
+	adminPaymentMethodRouter(r.Group("/admin/payment-methods"), db)
+}
+
+func authMiddleware(db *pgxpool.Pool) gin.HandlerFunc {
+	return middlewares.AuthMiddleware(models.NewUserRepository(db), models.NewSessionRepository(db), models.NewRevokedTokenRepository(db))
 }
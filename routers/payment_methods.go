@@ -0,0 +1,28 @@
+package routers
+
+import (
+	"backend-ewallet/controllers"
+	"backend-ewallet/middlewares"
+	"backend-ewallet/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func paymentMethodRouter(r *gin.RouterGroup, db *pgxpool.Pool) {
+	paymentMethodController := controllers.NewPaymentMethodController(db)
+
+	r.GET("", paymentMethodController.GetPaymentMethods)
+}
+
+func adminPaymentMethodRouter(r *gin.RouterGroup, db *pgxpool.Pool) {
+	paymentMethodController := controllers.NewPaymentMethodController(db)
+	r.Use(authMiddleware(db), middlewares.RequirePermission(models.PermissionPaymentMethodsManage))
+
+	r.GET("", paymentMethodController.GetAllPaymentMethods)
+	r.POST("", paymentMethodController.CreatePaymentMethod)
+	r.PATCH("/:id", paymentMethodController.UpdatePaymentMethod)
+	r.POST("/:id/enable", paymentMethodController.EnablePaymentMethod)
+	r.POST("/:id/disable", paymentMethodController.DisablePaymentMethod)
+	r.GET("/:id/versions", paymentMethodController.GetPaymentMethodVersions)
+}
@@ -3,15 +3,18 @@ package routers
 import (
 	"backend-ewallet/controllers"
 	"backend-ewallet/middlewares"
+	"backend-ewallet/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func transactionRouter(r *gin.RouterGroup) {
-	transactionController := controllers.NewTransactionController()
-	r.Use(middlewares.AuthMiddleware())
+func transactionRouter(r *gin.RouterGroup, db *pgxpool.Pool) {
+	transactionController := controllers.NewTransactionController(models.NewUserRepository(db), models.NewTransactionRepository(db))
+	r.Use(authMiddleware(db))
 
-	r.POST("/transfer", transactionController.Transfer)
-	r.POST("/topup", transactionController.Topup)
+	r.POST("/transfer", middlewares.RequireVerifiedAccount(), transactionController.Transfer)
+	r.POST("/topup", middlewares.RequireVerifiedAccount(), transactionController.Topup)
 	r.GET("/history", transactionController.GetTransactionHistory)
+	r.GET("/statement", transactionController.GetStatement)
 }
@@ -0,0 +1,22 @@
+package routers
+
+import (
+	"backend-ewallet/controllers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func userRouter(r *gin.RouterGroup, db *pgxpool.Pool) {
+	userController := controllers.NewUserController(db)
+	r.Use(authMiddleware(db))
+
+	r.GET("/me", userController.GetProfile)
+	r.PATCH("/me", userController.UpdateProfile)
+	r.PUT("/me/password", userController.ChangePassword)
+	r.PUT("/me/pin", userController.ChangePin)
+	r.POST("/me/pin/reset/request", userController.RequestPinReset)
+	r.POST("/me/pin/reset", userController.ResetPin)
+	r.POST("/me/close", userController.CloseAccount)
+	r.GET("/me/export", userController.ExportAccount)
+}
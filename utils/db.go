@@ -5,38 +5,80 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 )
 
-func ConnectDB() (*pgxpool.Conn, error) {
+// NewDBPool opens the application-wide connection pool. It is created once at
+// startup, handed to every repository and closed on shutdown. The pool is
+// tuned with DB_MAX_CONNS, DB_MIN_CONNS, DB_MAX_CONN_LIFETIME_MINUTES,
+// DB_MAX_CONN_IDLE_MINUTES, DB_HEALTH_CHECK_SECONDS and
+// DB_CONNECT_TIMEOUT_SECONDS, settings in DATABASE_URL (pool_max_conns, ...)
+// apply when those are unset.
+func NewDBPool() (*pgxpool.Pool, error) {
 	godotenv.Load()
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
 	}
 
-	pool, err := pgxpool.New(context.Background(), dbURL)
+	config, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DATABASE_URL: %w", err)
+	}
+
+	config.MaxConns = int32(GetEnvInt("DB_MAX_CONNS", int(config.MaxConns)))
+	config.MinConns = int32(GetEnvInt("DB_MIN_CONNS", int(config.MinConns)))
+	config.MaxConnLifetime = time.Duration(GetEnvInt("DB_MAX_CONN_LIFETIME_MINUTES", int(config.MaxConnLifetime/time.Minute))) * time.Minute
+	config.MaxConnIdleTime = time.Duration(GetEnvInt("DB_MAX_CONN_IDLE_MINUTES", int(config.MaxConnIdleTime/time.Minute))) * time.Minute
+	config.HealthCheckPeriod = time.Duration(GetEnvInt("DB_HEALTH_CHECK_SECONDS", int(config.HealthCheckPeriod/time.Second))) * time.Second
+	connectTimeout := time.Duration(GetEnvInt("DB_CONNECT_TIMEOUT_SECONDS", 5)) * time.Second
+	config.ConnConfig.ConnectTimeout = connectTimeout
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
-	if err := pool.Ping(context.Background()); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	conn, err := pool.Acquire(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to create connection from pool: %w", err)
-	}
+	log.Printf("Database pool ready (max %d, min %d connections)", config.MaxConns, config.MinConns)
+	return pool, nil
+}
 
-	log.Println("Database connection established successfully")
-	return conn, nil
+// DBPoolStats is the pool's state as reported by the health endpoint.
+type DBPoolStats struct {
+	MaxConns             int32  `json:"max_conns"`
+	TotalConns           int32  `json:"total_conns"`
+	AcquiredConns        int32  `json:"acquired_conns"`
+	IdleConns            int32  `json:"idle_conns"`
+	ConstructingConns    int32  `json:"constructing_conns"`
+	AcquireCount         int64  `json:"acquire_count"`
+	EmptyAcquireCount    int64  `json:"empty_acquire_count"`
+	CanceledAcquireCount int64  `json:"canceled_acquire_count"`
+	AcquireDuration      string `json:"acquire_duration"`
+	NewConnsCount        int64  `json:"new_conns_count"`
 }
 
-func CloseDB(pool *pgxpool.Conn) {
-	if pool != nil {
-		pool.Conn().Close(context.Background())
+func PoolStats(pool *pgxpool.Pool) DBPoolStats {
+	stat := pool.Stat()
+	return DBPoolStats{
+		MaxConns:             stat.MaxConns(),
+		TotalConns:           stat.TotalConns(),
+		AcquiredConns:        stat.AcquiredConns(),
+		IdleConns:            stat.IdleConns(),
+		ConstructingConns:    stat.ConstructingConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireDuration:      stat.AcquireDuration().String(),
+		NewConnsCount:        stat.NewConnsCount(),
 	}
 }
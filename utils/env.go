@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+)
+
+// GetEnvInt reads an integer env variable, falling back when unset or invalid.
+func GetEnvInt(key string, fallback int) int {
+	godotenv.Load()
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
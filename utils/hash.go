@@ -1,15 +1,178 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// PasswordHasher produces self-describing hashes: the algorithm and its
+// parameters are encoded in the hash, so a hash can always be verified and a
+// hash made with outdated parameters can be spotted.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Matches reports whether the hash was produced by this algorithm.
+	Matches(hash string) bool
+	Verify(password, hash string) bool
+	// NeedsRehash reports whether the hash was made with other parameters.
+	NeedsRehash(hash string) bool
+}
+
+// Argon2idHasher encodes hashes in the PHC string format,
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+var errInvalidArgon2Hash = errors.New("invalid argon2id hash")
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h Argon2idHasher) Matches(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (h Argon2idHasher) Verify(password, hash string) bool {
+	params, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return false
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1
+}
+
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+	return params.memory != h.Memory ||
+		params.iterations != h.Iterations ||
+		params.parallelism != h.Parallelism ||
+		uint32(len(params.salt)) != h.SaltLength ||
+		uint32(len(params.key)) != h.KeyLength
+}
+
+func decodeArgon2Hash(hash string) (*argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errInvalidArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, errInvalidArgon2Hash
+	}
+
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errInvalidArgon2Hash
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, errInvalidArgon2Hash
+	}
+	return params, nil
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
 	return string(bytes), err
 }
 
-func CheckPasswordHash(password, hash string) bool {
+func (h BcryptHasher) Matches(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (h BcryptHasher) Verify(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
+
+// passwordHasher is the hasher new hashes are made with, chosen by
+// PASSWORD_HASHER (argon2id by default, or bcrypt) and tuned with
+// ARGON2_MEMORY_KB, ARGON2_ITERATIONS, ARGON2_PARALLELISM and BCRYPT_COST.
+func passwordHasher() PasswordHasher {
+	if os.Getenv("PASSWORD_HASHER") == "bcrypt" {
+		return BcryptHasher{Cost: GetEnvInt("BCRYPT_COST", bcrypt.DefaultCost)}
+	}
+	return Argon2idHasher{
+		Memory:      uint32(GetEnvInt("ARGON2_MEMORY_KB", 19*1024)),
+		Iterations:  uint32(GetEnvInt("ARGON2_ITERATIONS", 2)),
+		Parallelism: uint8(GetEnvInt("ARGON2_PARALLELISM", 1)),
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// hasherFor finds the hasher able to verify the hash, whatever the current
+// configuration is.
+func hasherFor(hash string) PasswordHasher {
+	for _, hasher := range []PasswordHasher{passwordHasher(), Argon2idHasher{}, BcryptHasher{}} {
+		if hasher.Matches(hash) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+func HashPassword(password string) (string, error) {
+	return passwordHasher().Hash(password)
+}
+
+func CheckPasswordHash(password, hash string) bool {
+	hasher := hasherFor(hash)
+	return hasher != nil && hasher.Verify(password, hash)
+}
+
+// PasswordNeedsRehash reports whether the hash was made with another algorithm
+// or other parameters than the current ones. Call it after a successful
+// CheckPasswordHash and store a fresh HashPassword of the same input.
+func PasswordNeedsRehash(hash string) bool {
+	current := passwordHasher()
+	return !current.Matches(hash) || current.NeedsRehash(hash)
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// NormalizeEmail case-folds the address so the same mailbox can't be
+// registered twice with different capitalisation.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// defaultCountryCode is the calling code assumed for numbers written the
+// national way, PHONE_DEFAULT_COUNTRY_CODE, Indonesia (62) by default.
+func defaultCountryCode() string {
+	if code := strings.TrimPrefix(os.Getenv("PHONE_DEFAULT_COUNTRY_CODE"), "+"); code != "" {
+		return code
+	}
+	return "62"
+}
+
+// NormalizePhone turns a phone number into E.164 (+6281234567890). Spaces,
+// dashes, dots and brackets are ignored. A leading + or 00 means the country
+// code is given, a leading 0 is the national trunk prefix of the default
+// country, and a number already starting with the default country code is
+// taken as such, so 0812..., 62812..., +62 812... and 812... all agree.
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	international := strings.HasPrefix(phone, "+")
+
+	var digits strings.Builder
+	for i, r := range phone {
+		switch {
+		case unicode.IsDigit(r) && r < unicode.MaxASCII:
+			digits.WriteRune(r)
+		case r == '+' && i == 0, r == ' ', r == '-', r == '.', r == '(', r == ')':
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	number := digits.String()
+	countryCode := defaultCountryCode()
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case strings.HasPrefix(number, "0"):
+		number = countryCode + number[1:]
+	case !strings.HasPrefix(number, countryCode):
+		number = countryCode + number
+	}
+
+	// E.164 allows at most 15 digits, anything under 8 is not a subscriber number
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	return "+" + number, nil
+}
@@ -1,23 +1,51 @@
 package utils
 
 import (
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
 )
 
-func GenerateToken(userID int) (string, error) {
-	godotenv.Load()
-	expirationTime := time.Now().Add(24 * time.Hour)
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// AccessClaims describes who the access token is for and what it may do.
+type AccessClaims struct {
+	UserID       int
+	TokenVersion int
+	SessionID    int
+	Role         string
+	Permissions  []string
+}
+
+// GenerateToken signs the access token with the current signing key, the kid
+// header tells verifiers which key from the JWKS to use.
+func GenerateToken(access AccessClaims) (string, error) {
+	_, key, err := currentKeys()
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+
+	expirationTime := time.Now().Add(AccessTokenTTL)
 	claims := jwt.MapClaims{
-		"user_id": userID,
+		"jti":     jti,
+		"user_id": access.UserID,
+		"ver":     access.TokenVersion,
+		"sid":     access.SessionID,
+		"role":    access.Role,
+		"perms":   access.Permissions,
 		"iat":     time.Now().Unix(),
 		"exp":     expirationTime.Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KID
 
-	secretKey := os.Getenv("APP_SECRET")
-	return token.SignedString([]byte(secretKey))
+	return token.SignedString(key.Private)
 }
@@ -0,0 +1,420 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Every *.pem file in JWT_KEY_DIR is a key, its file name without the
+// extension is the kid. Private keys can sign, public keys only verify, which
+// is how a retired key is kept around until the tokens it signed expire. The
+// newest private key signs unless JWT_SIGNING_KID pins one.
+//
+// A key's age comes from its kid, which starts with its creation time for
+// generated keys (20060102T150405-<random>), never from the file's mtime,
+// so copying the directory or rebuilding an image doesn't change which key
+// signs. Keys named otherwise count as older than any generated key and are
+// never deleted automatically.
+
+// SigningKey is a key loaded from the key directory.
+type SigningKey struct {
+	KID       string
+	Method    jwt.SigningMethod
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+	CreatedAt time.Time
+}
+
+// JWK is the public part of a key as published in /.well-known/jwks.json.
+type JWK struct {
+	KID string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+const (
+	keyCheckInterval = 10 * time.Minute
+	// keyMissInterval spaces out the reloads triggered by tokens with an
+	// unknown kid, so made-up kids can't keep the service reading the disk.
+	keyMissInterval = 10 * time.Second
+
+	kidTimeLayout = "20060102T150405"
+)
+
+var (
+	keysMu     sync.RWMutex
+	keysLoaded bool
+	keys       map[string]*SigningKey
+	signingKey *SigningKey
+
+	keyMissMu     sync.Mutex
+	lastKeyMissAt time.Time
+)
+
+func keyDir() string {
+	if dir := os.Getenv("JWT_KEY_DIR"); dir != "" {
+		return dir
+	}
+	return "keys"
+}
+
+// keyAlgorithm is the algorithm used for keys generated by this service.
+func keyAlgorithm() string {
+	if alg := os.Getenv("JWT_ALG"); alg == jwt.SigningMethodRS256.Alg() {
+		return alg
+	}
+	return jwt.SigningMethodEdDSA.Alg()
+}
+
+// SigningMethods lists the algorithms accepted on incoming tokens.
+func SigningMethods() []string {
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
+
+// LoadSigningKeys (re)reads the key directory. An empty directory gets a
+// freshly generated key so a new install works out of the box.
+func LoadSigningKeys() error {
+	dir := keyDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	loaded, err := readKeyDir(dir)
+	if err != nil {
+		return err
+	}
+
+	if len(loaded) == 0 {
+		key, err := generateSigningKey(dir)
+		if err != nil {
+			return err
+		}
+		loaded[key.KID] = key
+	}
+
+	signer, err := pickSigningKey(loaded)
+	if err != nil {
+		return err
+	}
+
+	keysMu.Lock()
+	keys = loaded
+	signingKey = signer
+	keysLoaded = true
+	keysMu.Unlock()
+	return nil
+}
+
+func readKeyDir(dir string) (map[string]*SigningKey, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]*SigningKey, len(files))
+	for _, file := range files {
+		key, err := readKeyFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		loaded[key.KID] = key
+	}
+	return loaded, nil
+}
+
+func readKeyFile(file string) (*SigningKey, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	key := &SigningKey{KID: strings.TrimSuffix(filepath.Base(file), ".pem")}
+	key.CreatedAt = kidCreatedAt(key.KID)
+
+	var parsed any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, k.Public()
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return key, nil
+}
+
+// kidCreatedAt reads the creation time a generated kid starts with, zero for
+// keys named otherwise.
+func kidCreatedAt(kid string) time.Time {
+	if len(kid) < len(kidTimeLayout) {
+		return time.Time{}
+	}
+	created, err := time.Parse(kidTimeLayout, kid[:len(kidTimeLayout)])
+	if err != nil {
+		return time.Time{}
+	}
+	return created
+}
+
+func pickSigningKey(loaded map[string]*SigningKey) (*SigningKey, error) {
+	if kid := os.Getenv("JWT_SIGNING_KID"); kid != "" {
+		key, ok := loaded[kid]
+		if !ok || key.Private == nil {
+			return nil, fmt.Errorf("signing key %q has no private key in %s", kid, keyDir())
+		}
+		return key, nil
+	}
+
+	var newest *SigningKey
+	for _, key := range loaded {
+		if key.Private == nil {
+			continue
+		}
+		// Ties, such as two undated keys, go to the greater kid so every
+		// instance picks the same key
+		if newest == nil || key.CreatedAt.After(newest.CreatedAt) ||
+			(key.CreatedAt.Equal(newest.CreatedAt) && key.KID > newest.KID) {
+			newest = key
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("no private key found in %s", keyDir())
+	}
+	return newest, nil
+}
+
+// generateSigningKey writes a new private key into dir. The kid starts with
+// the creation time so the directory listing reads in rotation order.
+func generateSigningKey(dir string) (*SigningKey, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	createdAt := time.Now().UTC().Truncate(time.Second)
+	kid := fmt.Sprintf("%s-%x", createdAt.Format(kidTimeLayout), suffix)
+
+	var (
+		private crypto.Signer
+		method  jwt.SigningMethod
+		err     error
+	)
+	if keyAlgorithm() == jwt.SigningMethodRS256.Alg() {
+		method = jwt.SigningMethodRS256
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		method = jwt.SigningMethodEdDSA
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Generated %s signing key %s", method.Alg(), kid)
+	return &SigningKey{
+		KID:       kid,
+		Method:    method,
+		Private:   private,
+		Public:    private.Public(),
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// StartKeyRotation loads the keys and keeps them fresh in the background:
+// keys dropped into the directory by other instances are picked up, and once
+// the signing key is older than JWT_KEY_ROTATION_DAYS a new one is generated.
+// Generated keys are deleted once everything they signed has expired.
+// JWT_KEY_ROTATION_DAYS=0 leaves rotation to whoever manages the directory.
+func StartKeyRotation() error {
+	if err := LoadSigningKeys(); err != nil {
+		return err
+	}
+
+	rotation := time.Duration(GetEnvInt("JWT_KEY_ROTATION_DAYS", 30)) * 24 * time.Hour
+	go func() {
+		for range time.Tick(keyCheckInterval) {
+			if err := rotateSigningKeys(rotation); err != nil {
+				log.Printf("Failed to rotate signing keys: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func rotateSigningKeys(rotation time.Duration) error {
+	if err := LoadSigningKeys(); err != nil {
+		return err
+	}
+	if rotation <= 0 || os.Getenv("JWT_SIGNING_KID") != "" {
+		return nil
+	}
+
+	keysMu.RLock()
+	current := signingKey
+	keysMu.RUnlock()
+
+	if time.Since(current.CreatedAt) >= rotation {
+		if _, err := generateSigningKey(keyDir()); err != nil {
+			return err
+		}
+	}
+
+	// A key stops signing when its successor appears, one more rotation
+	// period is plenty for the last of its access tokens to expire.
+	keysMu.RLock()
+	for kid, key := range keys {
+		if kid == current.KID || key.Private == nil || key.CreatedAt.IsZero() ||
+			time.Since(key.CreatedAt) < 2*rotation+AccessTokenTTL {
+			continue
+		}
+		if err := os.Remove(filepath.Join(keyDir(), kid+".pem")); err != nil {
+			log.Printf("Failed to remove retired signing key %s: %v", kid, err)
+		}
+	}
+	keysMu.RUnlock()
+
+	return LoadSigningKeys()
+}
+
+func currentKeys() (map[string]*SigningKey, *SigningKey, error) {
+	keysMu.RLock()
+	loaded := keysLoaded
+	keysMu.RUnlock()
+
+	if !loaded {
+		if err := LoadSigningKeys(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	return keys, signingKey, nil
+}
+
+// reloadForKID rereads the key directory when a token names a kid we don't
+// know, which is how a key another instance just rotated in gets picked up
+// before the next check. At most one such reload runs per keyMissInterval.
+func reloadForKID(kid string) (*SigningKey, bool) {
+	keyMissMu.Lock()
+	if time.Since(lastKeyMissAt) < keyMissInterval {
+		keyMissMu.Unlock()
+		return nil, false
+	}
+	lastKeyMissAt = time.Now()
+	keyMissMu.Unlock()
+
+	if err := LoadSigningKeys(); err != nil {
+		log.Printf("Failed to reload signing keys for kid %q: %v", kid, err)
+		return nil, false
+	}
+
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// VerificationKey is the jwt.Keyfunc for our access tokens, it resolves the
+// kid header and refuses a token whose alg does not match the key.
+func VerificationKey(t *jwt.Token) (interface{}, error) {
+	loaded, _, err := currentKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key, ok := loaded[kid]
+	if !ok {
+		key, ok = reloadForKID(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+	return key.Public, nil
+}
+
+// PublicJWKS lists every verification key, sorted by kid.
+func PublicJWKS() (*JWKSet, error) {
+	loaded, _, err := currentKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	set := &JWKSet{Keys: []JWK{}}
+	for _, key := range loaded {
+		jwk := JWK{KID: key.KID, Alg: key.Method.Alg(), Use: "sig"}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KID < set.Keys[j].KID })
+	return set, nil
+}
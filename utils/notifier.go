@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+type Notifier interface {
+	Send(to string, subject string, body string) error
+}
+
+// ConsoleNotifier writes messages to the application log.
+type ConsoleNotifier struct{}
+
+func (n *ConsoleNotifier) Send(to string, subject string, body string) error {
+	log.Printf("[notifier] to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+// FileNotifier appends messages to a local file, handy for local development.
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (n *FileNotifier) Send(to string, subject string, body string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "[%s] to=%s subject=%q\n%s\n\n",
+		time.Now().Format(time.RFC3339), to, subject, body)
+	return err
+}
+
+// SMTPNotifier delivers messages as plain text email.
+type SMTPNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (n *SMTPNotifier) Send(to string, subject string, body string) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	msg := "From: " + n.From + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+	return smtp.SendMail(n.Host+":"+n.Port, auth, n.From, []string{to}, []byte(msg))
+}
+
+// NewNotifier picks an implementation based on the NOTIFIER env variable.
+func NewNotifier() Notifier {
+	godotenv.Load()
+	switch os.Getenv("NOTIFIER") {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPNotifier{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+	case "file":
+		path := os.Getenv("NOTIFIER_FILE")
+		if path == "" {
+			path = "notifications.log"
+		}
+		return &FileNotifier{Path: path}
+	default:
+		return &ConsoleNotifier{}
+	}
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var (
+	commonPasswordsOnce sync.Once
+	commonPasswords     map[string]struct{}
+)
+
+// PasswordPolicy describes what a password must look like.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// MinCharClasses is how many of lowercase, uppercase, digits and symbols
+	// the password has to mix.
+	MinCharClasses int
+	RejectCommon   bool
+}
+
+// DefaultPasswordPolicy reads the policy from PASSWORD_MIN_LENGTH,
+// PASSWORD_MIN_CHAR_CLASSES and PASSWORD_REJECT_COMMON.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:      GetEnvInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:      128,
+		MinCharClasses: GetEnvInt("PASSWORD_MIN_CHAR_CLASSES", 3),
+		RejectCommon:   GetEnvInt("PASSWORD_REJECT_COMMON", 1) != 0,
+	}
+}
+
+// Validate returns every rule the password breaks, empty when it is fine.
+// personal holds the user's email, phone and name, none of which may be part
+// of the password.
+func (p PasswordPolicy) Validate(password string, personal ...string) []string {
+	var violations []string
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+	}
+
+	if classes := charClasses(password); classes < p.MinCharClasses {
+		violations = append(violations, fmt.Sprintf(
+			"must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinCharClasses))
+	}
+
+	if containsPersonalInfo(password, personal) {
+		violations = append(violations, "must not contain your email, phone number or name")
+	}
+
+	if p.RejectCommon && IsCommonPassword(password) {
+		violations = append(violations, "is too common and appears in known password breaches")
+	}
+
+	return violations
+}
+
+func charClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// personalTokens splits email, phone and name into the pieces worth looking
+// for: the email's local part, the phone's last digits and each name part.
+func personalTokens(personal []string) []string {
+	var tokens []string
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if local, _, found := strings.Cut(value, "@"); found {
+			tokens = append(tokens, local)
+			continue
+		}
+
+		digits := strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, value)
+		if len(digits) >= 6 && strings.IndexFunc(value, unicode.IsLetter) < 0 {
+			tokens = append(tokens, digits[len(digits)-6:])
+			continue
+		}
+
+		tokens = append(tokens, strings.Fields(value)...)
+	}
+	return tokens
+}
+
+func containsPersonalInfo(secret string, personal []string) bool {
+	secret = strings.ToLower(secret)
+	for _, token := range personalTokens(personal) {
+		if len(token) >= 3 && strings.Contains(secret, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCommonPassword checks the bundled list of common and breached passwords,
+// also catching a listed word with digits or symbols tacked on the end.
+func IsCommonPassword(password string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = make(map[string]struct{})
+		for _, line := range strings.Split(commonPasswordList, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			commonPasswords[strings.ToLower(line)] = struct{}{}
+		}
+	})
+
+	lowered := strings.ToLower(password)
+	if _, found := commonPasswords[lowered]; found {
+		return true
+	}
+
+	base := strings.TrimRightFunc(lowered, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(base) >= 4 {
+		_, found := commonPasswords[base]
+		return found
+	}
+	return false
+}
+
+// ValidatePin returns every reason the PIN is too easy to guess: it has to be
+// 6 digits that are not one repeated digit, a run like 123456 or 987654, a
+// repeated pattern like 121212, or part of the user's phone number.
+func ValidatePin(pin string, personal ...string) []string {
+	var violations []string
+
+	if len(pin) != 6 || strings.IndexFunc(pin, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return append(violations, "must be exactly 6 digits")
+	}
+
+	if isSequentialPin(pin) {
+		violations = append(violations, "must not be a sequence like 123456 or 111111")
+	} else if isRepeatedPattern(pin) {
+		violations = append(violations, "must not repeat a pattern like 121212 or 123123")
+	} else if IsCommonPassword(pin) {
+		violations = append(violations, "is too common")
+	}
+
+	if containsPersonalInfo(pin, personal) {
+		violations = append(violations, "must not be part of your phone number")
+	}
+
+	return violations
+}
+
+// isSequentialPin catches the same digit repeated and runs going up or down
+// by one, wrapping around 9 and 0 (890123).
+func isSequentialPin(pin string) bool {
+	for _, step := range []int{0, 1, 9} {
+		sequential := true
+		for i := 1; i < len(pin); i++ {
+			if (int(pin[i]-'0')-int(pin[i-1]-'0')+10)%10 != step {
+				sequential = false
+				break
+			}
+		}
+		if sequential {
+			return true
+		}
+	}
+	return false
+}
+
+func isRepeatedPattern(pin string) bool {
+	for size := 1; size <= len(pin)/2; size++ {
+		if len(pin)%size == 0 && strings.Repeat(pin[:size], len(pin)/size) == pin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "log"
+
+type SMSSender interface {
+	SendSMS(phone string, message string) error
+}
+
+// ConsoleSMSSender logs messages instead of sending them, for local development.
+type ConsoleSMSSender struct{}
+
+func (s *ConsoleSMSSender) SendSMS(phone string, message string) error {
+	log.Printf("[sms] to=%s %s", phone, message)
+	return nil
+}
+
+// NewSMSSender returns the sender the app uses. There is no SMS provider
+// integration yet, so messages go to the log.
+func NewSMSSender() SMSSender {
+	return &ConsoleSMSSender{}
+}